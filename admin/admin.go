@@ -0,0 +1,129 @@
+// Package admin exposes a JSON-RPC control socket for live inspection
+// and management of a running relaygg process, similar in spirit to
+// yggdrasil-go's AdminSocket: handlers are registered by name, and each
+// connection is a newline-delimited JSON request/response exchange.
+package admin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+)
+
+// Handler answers one admin request. args carries the request's
+// positional string arguments; the returned value is marshaled as the
+// response's "result" field.
+type Handler func(args []string) (interface{}, error)
+
+// request is the newline-delimited JSON envelope a client sends.
+type request struct {
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+}
+
+// response is the newline-delimited JSON envelope the server replies
+// with. At most one of Result/Error is set; both are empty when a
+// handler succeeds with a nil result.
+type response struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Server is a registry of named admin handlers, servable over a
+// Unix-domain socket and/or a TCP listener.
+type Server struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewServer returns an empty Server; handlers are added with AddHandler.
+func NewServer() *Server {
+	return &Server{handlers: make(map[string]Handler)}
+}
+
+// AddHandler registers handler under name, replacing any previous
+// handler registered under the same name. args names the handler's
+// expected positional arguments at the registration site; Server
+// neither stores nor validates it.
+func (s *Server) AddHandler(name string, args []string, handler func(json.RawMessage) (interface{}, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[name] = func(rawArgs []string) (interface{}, error) {
+		encoded, err := json.Marshal(rawArgs)
+		if err != nil {
+			return nil, fmt.Errorf("encode args: %w", err)
+		}
+		return handler(encoded)
+	}
+}
+
+// ListenUnix serves the admin API on a Unix-domain socket at path,
+// replacing any stale socket file left behind by a previous run.
+func (s *Server) ListenUnix(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("admin: removing stale socket %s: %w", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("admin: listen unix %s: %w", path, err)
+	}
+	return s.serve(listener)
+}
+
+// ListenTCP serves the admin API on a TCP listener at addr. This is
+// opt-in and should only be bound to a trusted interface, since the
+// protocol carries no authentication of its own.
+func (s *Server) ListenTCP(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("admin: listen tcp %s: %w", addr, err)
+	}
+	return s.serve(listener)
+}
+
+func (s *Server) serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("admin: accept: %w", err)
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			encoder.Encode(response{Error: fmt.Sprintf("malformed request: %v", err)})
+			continue
+		}
+		encoder.Encode(s.dispatch(req))
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("❌ admin: connection error:", err)
+	}
+}
+
+func (s *Server) dispatch(req request) response {
+	s.mu.RLock()
+	handler, ok := s.handlers[req.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return response{Error: fmt.Sprintf("unknown command %q", req.Name)}
+	}
+	result, err := handler(req.Args)
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	return response{Result: result}
+}
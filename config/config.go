@@ -0,0 +1,124 @@
+// Package config loads and hot-reloads relaygg's runtime configuration
+// from an HJSON file, mirroring yggdrasil-go's own config conventions:
+// a human-editable HJSON source, decoded via mapstructure into a typed
+// Config, with a SIGHUP handler that re-reads the file in place.
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	hjson "github.com/hjson/hjson-go/v4"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Config is relaygg's full runtime configuration, read from an HJSON
+// file (or emitted as defaults via -genconf).
+type Config struct {
+	Peers          []string `mapstructure:"Peers"`
+	Listen         []string `mapstructure:"Listen"`
+	OOBListen      string   `mapstructure:"OOBListen"`
+	TLSProxyListen string   `mapstructure:"TLSProxyListen"`
+	SOCKS5Listen   string   `mapstructure:"SOCKS5Listen"`
+	MITMCAPath     string   `mapstructure:"MITMCAPath"`
+	ExitPolicy     string   `mapstructure:"ExitPolicy"`
+	PrivateKey     string   `mapstructure:"PrivateKey"`
+	AdminSocket    string   `mapstructure:"AdminSocket"`
+
+	// Obfuscator selects a registered obfs.Wrapper (e.g. "ntor") to wrap
+	// the OOB transport's connections, or "" for no obfuscation.
+	Obfuscator string `mapstructure:"Obfuscator"`
+
+	// PeerKeys maps a peer's Yggdrasil address to its long-term
+	// obfuscation public key (hex-encoded Curve25519), exchanged with
+	// that peer out of band the same way its address is. Only consulted
+	// when Obfuscator selects a wrapper that needs one, such as "ntor".
+	PeerKeys map[string]string `mapstructure:"PeerKeys"`
+}
+
+// Default returns the configuration written out by -genconf: sane
+// listen addresses, no peers yet, and a freshly generated ed25519
+// keypair for the node's Yggdrasil identity.
+func Default() (Config, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: generate keypair: %w", err)
+	}
+	return Config{
+		Peers:          nil,
+		Listen:         []string{"tls://0.0.0.0:0"},
+		OOBListen:      "0.0.0.0:7000",
+		TLSProxyListen: "127.0.0.1:8443",
+		SOCKS5Listen:   "127.0.0.1:1080",
+		MITMCAPath:     "mitm-ca.pem",
+		ExitPolicy:     "lowest-latency",
+		PrivateKey:     hex.EncodeToString(priv),
+		AdminSocket:    "", // opt-in: the admin protocol carries no authentication of its own
+		Obfuscator:     "", // opt-in: no obfuscation until a wrapper is selected
+		PeerKeys:       nil,
+	}, nil
+}
+
+// Load reads and decodes the HJSON config at path, tolerating a leading
+// UTF-8 byte order mark left behind by Windows-exported files.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: read %s: %w", path, err)
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	var raw map[string]interface{}
+	if err := hjson.Unmarshal(data, &raw); err != nil {
+		return Config{}, fmt.Errorf("config: parse %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := mapstructure.Decode(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("config: decode %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Marshal renders cfg back out as canonical HJSON, used by -normaliseconf
+// and when -genconf writes a fresh file.
+func Marshal(cfg Config) ([]byte, error) {
+	generic := map[string]interface{}{
+		"Peers":          cfg.Peers,
+		"Listen":         cfg.Listen,
+		"OOBListen":      cfg.OOBListen,
+		"TLSProxyListen": cfg.TLSProxyListen,
+		"SOCKS5Listen":   cfg.SOCKS5Listen,
+		"MITMCAPath":     cfg.MITMCAPath,
+		"ExitPolicy":     cfg.ExitPolicy,
+		"PrivateKey":     cfg.PrivateKey,
+		"AdminSocket":    cfg.AdminSocket,
+		"Obfuscator":     cfg.Obfuscator,
+		"PeerKeys":       cfg.PeerKeys,
+	}
+	return hjson.Marshal(generic)
+}
+
+// WatchSIGHUP re-reads path on every SIGHUP received and passes the
+// freshly parsed Config to onReload. Diffing the new config against
+// whatever is currently running (peers, listeners, ...) is onReload's
+// job, since only the caller knows which subsystems need updating.
+// WatchSIGHUP runs until the process exits; it does not return.
+func WatchSIGHUP(path string, onReload func(Config)) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		cfg, err := Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ config: SIGHUP reload of %s failed: %v\n", path, err)
+			continue
+		}
+		onReload(cfg)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// MITMConfig controls the on-the-fly leaf certificate minting used by
+// TLSProxy's MITM mode.
+type MITMConfig struct {
+	CACertPath    string
+	CAKeyPath     string
+	LeafValidity  time.Duration // default 24h
+	CipherSuites  []uint16      // allowlist passed to the client-facing tls.Config
+	LeafCacheSize int           // cap on the number of cached leaves (LRU eviction)
+}
+
+// defaultMITMConfig returns the MITM defaults used when a proxy is
+// constructed without explicit overrides.
+func defaultMITMConfig() MITMConfig {
+	return MITMConfig{
+		CACertPath:   "mitm-ca.pem",
+		CAKeyPath:    "mitm-ca.key",
+		LeafValidity: 24 * time.Hour,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		},
+		LeafCacheSize: 1024,
+	}
+}
+
+// cachedLeaf is a minted leaf certificate plus the time it was minted,
+// so certAuthority can tell when it needs replacing.
+type cachedLeaf struct {
+	cert     *tls.Certificate
+	mintedAt time.Time
+}
+
+// certAuthority mints per-host leaf certificates signed by a long-lived
+// local CA, caching each one until its LeafValidity has elapsed.
+type certAuthority struct {
+	cfg    MITMConfig
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	cache map[string]cachedLeaf
+	order []string // least recently used first, for the LRU cap
+}
+
+// loadOrCreateCA loads the CA key/cert pair from cfg's paths, generating
+// and persisting a new long-lived pair on first run.
+func loadOrCreateCA(cfg MITMConfig) (*certAuthority, error) {
+	caCert, caKey, err := readCAFiles(cfg.CACertPath, cfg.CAKeyPath)
+	if os.IsNotExist(err) {
+		caCert, caKey, err = generateCA(cfg.CACertPath, cfg.CAKeyPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mitm: loading CA: %w", err)
+	}
+	return &certAuthority{
+		cfg:    cfg,
+		caCert: caCert,
+		caKey:  caKey,
+		cache:  make(map[string]cachedLeaf),
+	}, nil
+}
+
+func readCAFiles(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("mitm: malformed CA PEM file")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func generateCA(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "relaygg local MITM CA", Organization: []string{"relaygg"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// leafFor returns a leaf certificate for host, minting and caching a
+// fresh one if none is cached or the cached one is past its validity.
+func (a *certAuthority) leafFor(host string) (*tls.Certificate, error) {
+	a.mu.Lock()
+	if entry, ok := a.cache[host]; ok && time.Since(entry.mintedAt) < a.cfg.LeafValidity {
+		// Move host to the most recently used end of order.
+		for i, h := range a.order {
+			if h == host {
+				a.order = append(a.order[:i], a.order[i+1:]...)
+				a.order = append(a.order, host)
+				break
+			}
+		}
+		a.mu.Unlock()
+		return entry.cert, nil
+	}
+	a.mu.Unlock()
+
+	leaf, err := a.mintLeaf(host)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache[host] = cachedLeaf{cert: leaf, mintedAt: time.Now()}
+	// Drop any stale position left behind by a previous mint of the same
+	// host, so the eviction below can't later delete the entry we just
+	// cached under a still-live duplicate.
+	for i, h := range a.order {
+		if h == host {
+			a.order = append(a.order[:i], a.order[i+1:]...)
+			break
+		}
+	}
+	a.order = append(a.order, host)
+	if len(a.order) > a.cfg.LeafCacheSize {
+		evict := a.order[0]
+		a.order = a.order[1:]
+		delete(a.cache, evict)
+	}
+	return leaf, nil
+}
+
+func (a *certAuthority) mintLeaf(host string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(a.cfg.LeafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.caCert, &key.PublicKey, a.caKey)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{der, a.caCert.Raw},
+		PrivateKey:  key,
+	}, nil
+}
+
+// exportCAPEM returns the CA certificate in PEM form so operators can
+// import it into a client's trust store.
+func (a *certAuthority) exportCAPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.caCert.Raw})
+}
+
+// flushCache discards every cached leaf, forcing the next leafFor call
+// for each host to mint a fresh one. Used by admin/flushCertCache.
+func (a *certAuthority) flushCache() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache = make(map[string]cachedLeaf)
+	a.order = nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCA builds a certAuthority backed by a freshly generated CA in a
+// scratch directory, with a small LeafCacheSize so eviction is easy to
+// exercise without minting thousands of leaves.
+func newTestCA(t *testing.T, cacheSize int) *certAuthority {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := defaultMITMConfig()
+	cfg.CACertPath = filepath.Join(dir, "ca.pem")
+	cfg.CAKeyPath = filepath.Join(dir, "ca.key")
+	cfg.LeafCacheSize = cacheSize
+	ca, err := loadOrCreateCA(cfg)
+	if err != nil {
+		t.Fatalf("loadOrCreateCA: %v", err)
+	}
+	return ca
+}
+
+func TestLeafForCachesByHost(t *testing.T) {
+	ca := newTestCA(t, 10)
+
+	leaf1, err := ca.leafFor("example.com")
+	if err != nil {
+		t.Fatalf("leafFor: %v", err)
+	}
+	leaf2, err := ca.leafFor("example.com")
+	if err != nil {
+		t.Fatalf("leafFor: %v", err)
+	}
+	if leaf1 != leaf2 {
+		t.Fatal("leafFor minted a new certificate for an already-cached host")
+	}
+}
+
+func TestLeafForEvictsOldestWhenFull(t *testing.T) {
+	ca := newTestCA(t, 2)
+
+	if _, err := ca.leafFor("a.com"); err != nil {
+		t.Fatalf("leafFor a.com: %v", err)
+	}
+	if _, err := ca.leafFor("b.com"); err != nil {
+		t.Fatalf("leafFor b.com: %v", err)
+	}
+	if _, err := ca.leafFor("c.com"); err != nil {
+		t.Fatalf("leafFor c.com: %v", err)
+	}
+
+	ca.mu.Lock()
+	_, stillCached := ca.cache["a.com"]
+	cacheLen := len(ca.cache)
+	orderLen := len(ca.order)
+	ca.mu.Unlock()
+
+	if stillCached {
+		t.Fatal("leafFor did not evict the oldest host once the cache was full")
+	}
+	if cacheLen != 2 || orderLen != 2 {
+		t.Fatalf("cache/order grew past LeafCacheSize: cache=%d order=%d", cacheLen, orderLen)
+	}
+}
+
+// TestLeafForReMintDoesNotLeaveDuplicateOrderEntry guards the dedupe fix:
+// re-minting a host (because its cached leaf expired) must not leave a
+// stale copy of that host in order, or a later eviction could delete the
+// fresh entry while the stale copy lingers in cache forever.
+func TestLeafForReMintDoesNotLeaveDuplicateOrderEntry(t *testing.T) {
+	ca := newTestCA(t, 3)
+	ca.cfg.LeafValidity = time.Millisecond
+
+	if _, err := ca.leafFor("a.com"); err != nil {
+		t.Fatalf("leafFor a.com (1st mint): %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := ca.leafFor("a.com"); err != nil {
+		t.Fatalf("leafFor a.com (re-mint): %v", err)
+	}
+
+	ca.mu.Lock()
+	count := 0
+	for _, h := range ca.order {
+		if h == "a.com" {
+			count++
+		}
+	}
+	ca.mu.Unlock()
+
+	if count != 1 {
+		t.Fatalf("expected exactly one order entry for a.com after re-mint, got %d", count)
+	}
+}
@@ -0,0 +1,253 @@
+package obfs
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ntorInfoLabel domain-separates the HKDF used to derive ntor session
+// keys, so this handshake's output can never collide with another
+// protocol's use of the same shared secrets.
+const ntorInfoLabel = "relaygg-ntor-v1"
+
+// maxNtorChunk bounds how many plaintext bytes a single sealed frame
+// carries, keeping frames well under the 2-byte length prefix's range.
+const maxNtorChunk = 16 * 1024
+
+// maxNtorPad bounds the random padding appended to each frame.
+const maxNtorPad = 64
+
+// NodeKeyPair is a node's long-term Curve25519 identity, published
+// alongside its Yggdrasil address so peers can ntor-handshake against it.
+type NodeKeyPair struct {
+	Public  [32]byte
+	Private [32]byte
+}
+
+// GenerateNodeKeyPair creates a fresh Curve25519 keypair, used both for
+// long-term node identities and per-connection ephemeral keys.
+func GenerateNodeKeyPair() (NodeKeyPair, error) {
+	var kp NodeKeyPair
+	if _, err := io.ReadFull(rand.Reader, kp.Private[:]); err != nil {
+		return kp, fmt.Errorf("obfs: generate node key: %w", err)
+	}
+	pub, err := curve25519.X25519(kp.Private[:], curve25519.Basepoint)
+	if err != nil {
+		return kp, fmt.Errorf("obfs: derive public key: %w", err)
+	}
+	copy(kp.Public[:], pub)
+	return kp, nil
+}
+
+// NtorWrapper performs an obfs4-style ntor handshake (client ephemeral ×
+// server static, server ephemeral × client ephemeral) before framing
+// traffic as ChaCha20-Poly1305 sealed, randomly padded records with
+// per-direction keys and nonces.
+type NtorWrapper struct {
+	// NodeKey is this node's long-term Curve25519 identity. Required by
+	// Server(); unused by Client().
+	NodeKey NodeKeyPair
+
+	// PeerPublicKey resolves a dialed peer's long-term public key from
+	// its Yggdrasil address. Required by Client(); unused by Server().
+	PeerPublicKey func(peerAddr string) ([32]byte, bool)
+}
+
+func init() {
+	Register("ntor", &NtorWrapper{})
+}
+
+func (w *NtorWrapper) Client(conn net.Conn) (net.Conn, error) {
+	if w.PeerPublicKey == nil {
+		return nil, fmt.Errorf("obfs: ntor client requires PeerPublicKey")
+	}
+	serverStatic, ok := w.PeerPublicKey(conn.RemoteAddr().String())
+	if !ok {
+		return nil, fmt.Errorf("obfs: no known ntor public key for %s", conn.RemoteAddr())
+	}
+
+	clientEph, err := GenerateNodeKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(clientEph.Public[:]); err != nil {
+		return nil, fmt.Errorf("obfs: send client ephemeral: %w", err)
+	}
+	var serverEphPub [32]byte
+	if _, err := io.ReadFull(conn, serverEphPub[:]); err != nil {
+		return nil, fmt.Errorf("obfs: read server ephemeral: %w", err)
+	}
+
+	secret1, err := scalarMult(clientEph.Private, serverStatic)
+	if err != nil {
+		return nil, err
+	}
+	secret2, err := scalarMult(clientEph.Private, serverEphPub)
+	if err != nil {
+		return nil, err
+	}
+	clientToServer, serverToClient, err := deriveSessionKeys(secret1, secret2)
+	if err != nil {
+		return nil, err
+	}
+	return newNtorConn(conn, clientToServer, serverToClient)
+}
+
+func (w *NtorWrapper) Server(conn net.Conn) (net.Conn, error) {
+	var clientEphPub [32]byte
+	if _, err := io.ReadFull(conn, clientEphPub[:]); err != nil {
+		return nil, fmt.Errorf("obfs: read client ephemeral: %w", err)
+	}
+	serverEph, err := GenerateNodeKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(serverEph.Public[:]); err != nil {
+		return nil, fmt.Errorf("obfs: send server ephemeral: %w", err)
+	}
+
+	secret1, err := scalarMult(w.NodeKey.Private, clientEphPub)
+	if err != nil {
+		return nil, err
+	}
+	secret2, err := scalarMult(serverEph.Private, clientEphPub)
+	if err != nil {
+		return nil, err
+	}
+	clientToServer, serverToClient, err := deriveSessionKeys(secret1, secret2)
+	if err != nil {
+		return nil, err
+	}
+	return newNtorConn(conn, serverToClient, clientToServer)
+}
+
+func scalarMult(priv, pub [32]byte) ([32]byte, error) {
+	var out [32]byte
+	secret, err := curve25519.X25519(priv[:], pub[:])
+	if err != nil {
+		return out, fmt.Errorf("obfs: scalar mult: %w", err)
+	}
+	copy(out[:], secret)
+	return out, nil
+}
+
+// deriveSessionKeys feeds both ntor DH outputs through HKDF-SHA256 to
+// produce the two per-direction keys.
+func deriveSessionKeys(secret1, secret2 [32]byte) (clientToServer, serverToClient [32]byte, err error) {
+	combined := append(append([]byte{}, secret1[:]...), secret2[:]...)
+	kdf := hkdf.New(sha256.New, combined, nil, []byte(ntorInfoLabel))
+	var out [64]byte
+	if _, err := io.ReadFull(kdf, out[:]); err != nil {
+		return clientToServer, serverToClient, fmt.Errorf("obfs: hkdf: %w", err)
+	}
+	copy(clientToServer[:], out[:32])
+	copy(serverToClient[:], out[32:])
+	return clientToServer, serverToClient, nil
+}
+
+// ntorConn wraps conn with ChaCha20-Poly1305 framing once the ntor
+// handshake has established sendKey/recvKey. Each frame is
+// [2-byte length][ChaCha20-Poly1305-sealed plaintext], where the sealed
+// plaintext is itself [2-byte payload length][payload][random padding].
+type ntorConn struct {
+	net.Conn
+	send      cipher.AEAD
+	recv      cipher.AEAD
+	sendNonce uint64
+	recvNonce uint64
+	pending   []byte
+}
+
+func newNtorConn(conn net.Conn, sendKey, recvKey [32]byte) (*ntorConn, error) {
+	send, err := chacha20poly1305.New(sendKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("obfs: send aead: %w", err)
+	}
+	recv, err := chacha20poly1305.New(recvKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("obfs: recv aead: %w", err)
+	}
+	return &ntorConn{Conn: conn, send: send, recv: recv}, nil
+}
+
+func (c *ntorConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		hdr := make([]byte, 2)
+		if _, err := io.ReadFull(c.Conn, hdr); err != nil {
+			return 0, err
+		}
+		sealed := make([]byte, binary.BigEndian.Uint16(hdr))
+		if _, err := io.ReadFull(c.Conn, sealed); err != nil {
+			return 0, err
+		}
+		plaintext, err := c.recv.Open(nil, c.nextNonce(&c.recvNonce), sealed, nil)
+		if err != nil {
+			return 0, fmt.Errorf("obfs: open frame: %w", err)
+		}
+		if len(plaintext) < 2 {
+			return 0, fmt.Errorf("obfs: short frame plaintext")
+		}
+		payloadLen := binary.LittleEndian.Uint16(plaintext[:2])
+		if int(payloadLen) > len(plaintext)-2 {
+			return 0, fmt.Errorf("obfs: payload length exceeds frame")
+		}
+		c.pending = plaintext[2 : 2+payloadLen]
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *ntorConn) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > maxNtorChunk {
+			n = maxNtorChunk
+		}
+		padLen, err := randPadLen(maxNtorPad)
+		if err != nil {
+			return total, err
+		}
+		plaintext := make([]byte, 2+n+padLen)
+		binary.LittleEndian.PutUint16(plaintext[:2], uint16(n))
+		copy(plaintext[2:2+n], p[:n])
+		if padLen > 0 {
+			if _, err := rand.Read(plaintext[2+n:]); err != nil {
+				return total, err
+			}
+		}
+
+		sealed := c.send.Seal(nil, c.nextNonce(&c.sendNonce), plaintext, nil)
+		if len(sealed) > 0xFFFF {
+			return total, fmt.Errorf("obfs: sealed frame too large: %d bytes", len(sealed))
+		}
+		hdr := make([]byte, 2)
+		binary.BigEndian.PutUint16(hdr, uint16(len(sealed)))
+		if _, err := c.Conn.Write(append(hdr, sealed...)); err != nil {
+			return total, err
+		}
+
+		total += n
+		p = p[n:]
+	}
+	return total, nil
+}
+
+// nextNonce builds the next ChaCha20-Poly1305 nonce for a direction from
+// its monotonically increasing counter, then advances it.
+func (c *ntorConn) nextNonce(counter *uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(nonce[4:], *counter)
+	*counter++
+	return nonce
+}
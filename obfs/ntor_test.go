@@ -0,0 +1,84 @@
+package obfs
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+// TestNtorHandshakeRoundTrip drives a full client/server ntor handshake
+// over an in-memory pipe and checks that application data survives the
+// sealed, padded framing in both directions.
+func TestNtorHandshakeRoundTrip(t *testing.T) {
+	serverKey, err := GenerateNodeKeyPair()
+	if err != nil {
+		t.Fatalf("generate server key: %v", err)
+	}
+
+	client := &NtorWrapper{
+		PeerPublicKey: func(string) ([32]byte, bool) { return serverKey.Public, true },
+	}
+	server := &NtorWrapper{NodeKey: serverKey}
+
+	clientRaw, serverRaw := net.Pipe()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	clientDone := make(chan result, 1)
+	serverDone := make(chan result, 1)
+
+	go func() {
+		conn, err := client.Client(clientRaw)
+		clientDone <- result{conn, err}
+	}()
+	go func() {
+		conn, err := server.Server(serverRaw)
+		serverDone <- result{conn, err}
+	}()
+
+	clientConn := <-clientDone
+	if clientConn.err != nil {
+		t.Fatalf("client handshake: %v", clientConn.err)
+	}
+	serverConn := <-serverDone
+	if serverConn.err != nil {
+		t.Fatalf("server handshake: %v", serverConn.err)
+	}
+
+	const msg = "hello over ntor"
+	go clientConn.conn.Write([]byte(msg))
+
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(serverConn.conn, buf); err != nil {
+		t.Fatalf("server read: %v", err)
+	}
+	if string(buf) != msg {
+		t.Fatalf("got %q, want %q", buf, msg)
+	}
+
+	const reply = "hello back"
+	go serverConn.conn.Write([]byte(reply))
+
+	buf2 := make([]byte, len(reply))
+	if _, err := io.ReadFull(clientConn.conn, buf2); err != nil {
+		t.Fatalf("client read: %v", err)
+	}
+	if string(buf2) != reply {
+		t.Fatalf("got %q, want %q", buf2, reply)
+	}
+}
+
+// TestNtorClientRequiresPeerPublicKey checks the clear error returned
+// when a caller forgets to wire a PeerPublicKey resolver.
+func TestNtorClientRequiresPeerPublicKey(t *testing.T) {
+	client := &NtorWrapper{}
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	if _, err := client.Client(a); err == nil {
+		t.Fatal("expected an error when PeerPublicKey is nil")
+	}
+}
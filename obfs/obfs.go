@@ -0,0 +1,51 @@
+// Package obfs implements a pluggable obfuscation layer for the OOB QUIC
+// transport, modeled on Tor's pluggable-transport interface. A Wrapper
+// wraps a raw net.Conn so the bytes on the wire no longer look like bare
+// OOB framing, letting the relay keep working on networks that
+// fingerprint or block QUIC/JSON traffic directly.
+package obfs
+
+import (
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Wrapper obfuscates a raw connection for transport and removes that
+// obfuscation again on the other side. Client and Server mirror which
+// end of the connection performed the dial, since most obfuscators use
+// an asymmetric handshake.
+type Wrapper interface {
+	// Client wraps a freshly dialed outbound connection, performing
+	// whatever handshake the obfuscator needs before returning a conn
+	// that behaves like a plain net.Conn to the caller.
+	Client(conn net.Conn) (net.Conn, error)
+
+	// Server wraps a freshly accepted inbound connection, completing
+	// the server side of the obfuscator's handshake.
+	Server(conn net.Conn) (net.Conn, error)
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]Wrapper{}
+)
+
+// Register adds a named Wrapper to the registry so it can be selected by
+// name from config. Re-registering a name replaces the previous entry.
+func Register(name string, w Wrapper) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = w
+}
+
+// Get looks up a Wrapper previously added with Register.
+func Get(name string) (Wrapper, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	w, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("obfs: no wrapper registered for %q", name)
+	}
+	return w, nil
+}
@@ -0,0 +1,95 @@
+package obfs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+func init() {
+	Register("xorpad", &XORPadWrapper{Key: 0x5A})
+}
+
+// XORPadWrapper is a minimal obfuscator that XORs every byte with a
+// fixed key and pads each write with a random amount of junk. It
+// requires no handshake, so Client and Server are identical. It offers
+// no real confidentiality or authentication and exists mainly as a
+// cheap option and as a template for additional Wrappers; use
+// NtorWrapper when the traffic actually needs to resist analysis.
+type XORPadWrapper struct {
+	Key byte
+}
+
+func (w *XORPadWrapper) Client(conn net.Conn) (net.Conn, error) {
+	return &xorPadConn{Conn: conn, key: w.Key}, nil
+}
+
+func (w *XORPadWrapper) Server(conn net.Conn) (net.Conn, error) {
+	return &xorPadConn{Conn: conn, key: w.Key}, nil
+}
+
+type xorPadConn struct {
+	net.Conn
+	key     byte
+	pending []byte
+}
+
+func (c *xorPadConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		hdr := make([]byte, 4)
+		if _, err := io.ReadFull(c.Conn, hdr); err != nil {
+			return 0, err
+		}
+		frameLen := binary.BigEndian.Uint16(hdr[:2])
+		payloadLen := binary.BigEndian.Uint16(hdr[2:])
+		if payloadLen > frameLen {
+			return 0, fmt.Errorf("obfs: xorpad payload length %d exceeds frame length %d", payloadLen, frameLen)
+		}
+		frame := make([]byte, frameLen)
+		if _, err := io.ReadFull(c.Conn, frame); err != nil {
+			return 0, err
+		}
+		payload := frame[:payloadLen]
+		for i := range payload {
+			payload[i] ^= c.key
+		}
+		c.pending = payload
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *xorPadConn) Write(p []byte) (int, error) {
+	padLen, err := randPadLen(32)
+	if err != nil {
+		return 0, err
+	}
+	frame := make([]byte, len(p)+padLen)
+	copy(frame, p)
+	if padLen > 0 {
+		if _, err := rand.Read(frame[len(p):]); err != nil {
+			return 0, err
+		}
+	}
+	for i := range frame {
+		frame[i] ^= c.key
+	}
+	hdr := make([]byte, 4)
+	binary.BigEndian.PutUint16(hdr[:2], uint16(len(frame)))
+	binary.BigEndian.PutUint16(hdr[2:], uint16(len(p)))
+	if _, err := c.Conn.Write(append(hdr, frame...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func randPadLen(max byte) (int, error) {
+	var b [1]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, fmt.Errorf("obfs: random padding: %w", err)
+	}
+	return int(b[0] % max), nil
+}
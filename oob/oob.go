@@ -0,0 +1,806 @@
+package oob
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gologme "github.com/gologme/log"
+	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+	"github.com/yggdrasil-network/yggquic"
+
+	"github.com/immartian/relaygg/obfs"
+)
+
+// envelopeKind identifies the kind of message carried by an envelope on
+// the OOB wire.
+type envelopeKind string
+
+const (
+	kindRequest  envelopeKind = "request"
+	kindResponse envelopeKind = "response"
+	kindDial     envelopeKind = "dial"
+	kindData     envelopeKind = "data"
+	kindClose    envelopeKind = "close"
+)
+
+// envelope is the typed message multiplexed over an OOB QUIC stream. Each
+// envelope is framed on the wire as a 4-byte big-endian length prefix
+// followed by its JSON encoding, so a stream can carry many envelopes
+// (possibly for different RequestIDs) without truncating at a fixed size.
+type envelope struct {
+	Type      envelopeKind    `json:"type"`
+	RequestID string          `json:"request_id"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// requestPayload carries the data of a "request"/"response" envelope.
+type requestPayload struct {
+	Data string `json:"data"`
+}
+
+// dialPayload carries the "host:port" target of a "dial" envelope.
+type dialPayload struct {
+	Target string `json:"target"`
+}
+
+// dataPayload carries one chunk of a spliced "data" envelope.
+type dataPayload struct {
+	Chunk []byte `json:"chunk"`
+}
+
+const (
+	// maxFrameSize bounds a single envelope's encoded JSON body, guarding
+	// against a malformed or malicious length prefix forcing a huge read.
+	maxFrameSize = 1 << 20 // 1 MiB
+
+	// frameDeadline bounds how long a single frame read or write may
+	// block, so a stalled peer can't wedge a goroutine forever.
+	frameDeadline = 30 * time.Second
+
+	// maxDataChunk bounds how many raw bytes a single "data" envelope
+	// carries, keeping its base64-inflated JSON body under maxFrameSize.
+	maxDataChunk = 256 * 1024
+)
+
+// writeFrame encodes env and writes it as a length-prefixed frame.
+func writeFrame(conn net.Conn, env envelope) error {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("encode frame: %w", err)
+	}
+	if len(body) > maxFrameSize {
+		return fmt.Errorf("frame too large: %d bytes", len(body))
+	}
+	if err := conn.SetWriteDeadline(time.Now().Add(frameDeadline)); err != nil {
+		return fmt.Errorf("set write deadline: %w", err)
+	}
+	hdr := make([]byte, 4)
+	binary.BigEndian.PutUint32(hdr, uint32(len(body)))
+	if _, err := conn.Write(append(hdr, body...)); err != nil {
+		return fmt.Errorf("write frame: %w", err)
+	}
+	return nil
+}
+
+// readFrame reads and decodes one length-prefixed frame from conn.
+func readFrame(conn net.Conn) (envelope, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(frameDeadline)); err != nil {
+		return envelope{}, fmt.Errorf("set read deadline: %w", err)
+	}
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return envelope{}, err
+	}
+	size := binary.BigEndian.Uint32(hdr)
+	if size > maxFrameSize {
+		return envelope{}, fmt.Errorf("frame exceeds maxFrameSize: %d bytes", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return envelope{}, fmt.Errorf("read frame body: %w", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return envelope{}, fmt.Errorf("decode frame: %w", err)
+	}
+	return env, nil
+}
+
+// framedConn adapts a raw OOB net.Conn so callers can Read/Write plain
+// deframed bytes after a dial handshake, while the wire underneath still
+// carries typed "data" envelopes tagged with requestID.
+type framedConn struct {
+	net.Conn
+	requestID string
+	pending   []byte
+	stat      *peerStat // optional; set by OpenExit to feed admin/getPeers counters
+}
+
+func (c *framedConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		env, err := readFrame(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+		switch env.Type {
+		case kindData:
+			var pl dataPayload
+			if err := json.Unmarshal(env.Payload, &pl); err != nil {
+				return 0, fmt.Errorf("decode data frame: %w", err)
+			}
+			c.pending = pl.Chunk
+		case kindClose:
+			return 0, io.EOF
+		default:
+			// Ignore stray control frames mid-splice.
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	if c.stat != nil {
+		atomic.AddUint64(&c.stat.bytesIn, uint64(n))
+	}
+	return n, nil
+}
+
+func (c *framedConn) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > maxDataChunk {
+			n = maxDataChunk
+		}
+		payload, err := json.Marshal(dataPayload{Chunk: p[:n]})
+		if err != nil {
+			return total, fmt.Errorf("encode data frame: %w", err)
+		}
+		if err := writeFrame(c.Conn, envelope{Type: kindData, RequestID: c.requestID, Payload: payload}); err != nil {
+			return total, err
+		}
+		total += n
+		p = p[n:]
+	}
+	if c.stat != nil {
+		atomic.AddUint64(&c.stat.bytesOut, uint64(total))
+	}
+	return total, nil
+}
+
+func (c *framedConn) Close() error {
+	_ = writeFrame(c.Conn, envelope{Type: kindClose, RequestID: c.requestID})
+	return c.Conn.Close()
+}
+
+// OOBModule handles OOB communication via QUIC.
+type OOBModule struct {
+	Node       *core.Core
+	Transport  *yggquic.YggdrasilTransport
+	Peers      []string
+	Wrapper    obfs.Wrapper // optional pluggable transport, nil means no obfuscation
+	NodeKey    obfs.NodeKeyPair
+	ExitPolicy string // how ExitPeer picks among Peers; see ExitPeer
+	PublicKey  ed25519.PublicKey
+	StartTime  time.Time
+	mu         sync.Mutex
+	requestMap sync.Map // Maps request IDs to *pendingRequest
+	peerStats  sync.Map // Maps peer address to *peerStat
+	peerKeys   sync.Map // Maps peer address to [32]byte obfuscation public key
+}
+
+// peerStat tracks the best-effort traffic counters and latency this
+// module has observed for one peer, for admin/getPeers. BytesIn/BytesOut
+// are updated by the framedConn returned from OpenExit; RTT is updated
+// by SendOOBRequest.
+type peerStat struct {
+	bytesIn  uint64
+	bytesOut uint64
+	rtt      int64 // time.Duration, stored via atomic
+}
+
+// statFor returns the peerStat for peer, creating one on first use.
+func (o *OOBModule) statFor(peer string) *peerStat {
+	v, _ := o.peerStats.LoadOrStore(peer, &peerStat{})
+	return v.(*peerStat)
+}
+
+// pendingRequest is the value stored in requestMap: the channel
+// SendOOBRequest is waiting on, plus when the request was sent.
+type pendingRequest struct {
+	ch      chan string
+	started time.Time
+}
+
+// SelfSummary describes this node's identity and uptime, for
+// admin/getSelf.
+type SelfSummary struct {
+	Address       string        `json:"address"`
+	PublicKey     string        `json:"public_key"`
+	ObfsPublicKey string        `json:"obfs_public_key"`
+	Uptime        time.Duration `json:"uptime"`
+}
+
+// Self summarizes this node's Yggdrasil address, long-term public key,
+// obfuscation public key (for peers that want to obfs-wrap traffic to
+// this node, shared out of band the same way Address is), and uptime.
+func (o *OOBModule) Self() SelfSummary {
+	var addr string
+	if o.Node != nil {
+		addr = o.Node.Address().String()
+	}
+	return SelfSummary{
+		Address:       addr,
+		PublicKey:     hex.EncodeToString(o.PublicKey),
+		ObfsPublicKey: hex.EncodeToString(o.NodeKey.Public[:]),
+		Uptime:        time.Since(o.StartTime),
+	}
+}
+
+// RequestSnapshot describes one in-flight SendOOBRequest call, for
+// admin/getRequests.
+type RequestSnapshot struct {
+	RequestID string        `json:"request_id"`
+	Age       time.Duration `json:"age"`
+}
+
+// Requests returns a snapshot of every SendOOBRequest call still
+// awaiting a response.
+func (o *OOBModule) Requests() []RequestSnapshot {
+	var out []RequestSnapshot
+	o.requestMap.Range(func(key, value interface{}) bool {
+		pr := value.(*pendingRequest)
+		out = append(out, RequestSnapshot{
+			RequestID: key.(string),
+			Age:       time.Since(pr.started),
+		})
+		return true
+	})
+	return out
+}
+
+// PeerSummary describes one configured peer's observed traffic and
+// latency, for admin/getPeers.
+type PeerSummary struct {
+	Address  string        `json:"address"`
+	BytesIn  uint64        `json:"bytes_in"`
+	BytesOut uint64        `json:"bytes_out"`
+	RTT      time.Duration `json:"rtt"`
+}
+
+// PeerSummaries returns the current peer list together with whatever
+// traffic counters and latency this module has observed for each one so
+// far; a peer never dialed through OpenExit or SendOOBRequest reports
+// zeroes.
+func (o *OOBModule) PeerSummaries() []PeerSummary {
+	o.mu.Lock()
+	peers := append([]string(nil), o.Peers...)
+	o.mu.Unlock()
+
+	out := make([]PeerSummary, len(peers))
+	for i, p := range peers {
+		stat := o.statFor(p)
+		out[i] = PeerSummary{
+			Address:  p,
+			BytesIn:  atomic.LoadUint64(&stat.bytesIn),
+			BytesOut: atomic.LoadUint64(&stat.bytesOut),
+			RTT:      time.Duration(atomic.LoadInt64(&stat.rtt)),
+		}
+	}
+	return out
+}
+
+// AddPeer appends peer to the running peer list if it isn't already
+// present.
+func (o *OOBModule) AddPeer(peer string) error {
+	if !isValidYggdrasilAddress(peer) {
+		return fmt.Errorf("invalid Yggdrasil address: %s", peer)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, p := range o.Peers {
+		if p == peer {
+			return nil
+		}
+	}
+	o.Peers = append(o.Peers, peer)
+	log.Println("🔹 OOB peer added:", peer)
+	return nil
+}
+
+// RemovePeer removes peer from the running peer list, if present, along
+// with any traffic counters recorded for it.
+func (o *OOBModule) RemovePeer(peer string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.peerStats.Delete(peer)
+	for i, p := range o.Peers {
+		if p == peer {
+			o.Peers = append(o.Peers[:i], o.Peers[i+1:]...)
+			log.Println("🔹 OOB peer removed:", peer)
+			return
+		}
+	}
+}
+
+// ExitPeer picks a peer to use as an OOB exit according to o.ExitPolicy,
+// and reports whether a peer was available at all. ExitPolicy
+// "lowest-latency" (the config default) picks the peer with the lowest
+// RTT observed so far by SendOOBRequest, falling back to the first
+// configured peer until any RTT has been measured. Any other value (or
+// an empty ExitPolicy) just returns the first configured peer.
+func (o *OOBModule) ExitPeer() (string, bool) {
+	o.mu.Lock()
+	peers := append([]string(nil), o.Peers...)
+	policy := o.ExitPolicy
+	o.mu.Unlock()
+
+	if len(peers) == 0 {
+		return "", false
+	}
+	if policy != "lowest-latency" {
+		return peers[0], true
+	}
+
+	best := peers[0]
+	var bestRTT time.Duration
+	for _, p := range peers {
+		rtt := time.Duration(atomic.LoadInt64(&o.statFor(p).rtt))
+		if rtt <= 0 {
+			continue
+		}
+		if bestRTT == 0 || rtt < bestRTT {
+			best, bestRTT = p, rtt
+		}
+	}
+	return best, true
+}
+
+// SetPeerKeys replaces the known peer obfuscation public keys, used by
+// an obfuscator (e.g. "ntor") that needs each peer's long-term key to
+// dial out. Keys are hex-encoded Curve25519 public keys, shared out of
+// band the same way a peer's Yggdrasil address is. Malformed entries are
+// logged and skipped rather than rejecting the whole update.
+func (o *OOBModule) SetPeerKeys(keys map[string]string) {
+	o.peerKeys.Range(func(addr, _ interface{}) bool {
+		if _, ok := keys[addr.(string)]; !ok {
+			o.peerKeys.Delete(addr)
+		}
+		return true
+	})
+	for addr, hexKey := range keys {
+		raw, err := hex.DecodeString(hexKey)
+		if err != nil || len(raw) != 32 {
+			log.Printf("⚠️ Ignoring malformed obfuscation key for peer %s", addr)
+			continue
+		}
+		var key [32]byte
+		copy(key[:], raw)
+		o.peerKeys.Store(addr, key)
+	}
+}
+
+// resolvePeerKey looks up a peer's obfuscation public key, previously
+// set via SetPeerKeys. It is used as an obfs.NtorWrapper's
+// PeerPublicKey callback.
+func (o *OOBModule) resolvePeerKey(peerAddr string) ([32]byte, bool) {
+	v, ok := o.peerKeys.Load(peerAddr)
+	if !ok {
+		return [32]byte{}, false
+	}
+	return v.([32]byte), true
+}
+
+// SetObfuscator selects the registered obfs.Wrapper named name to wrap
+// every OOB connection this module makes or accepts from here on, or
+// clears obfuscation entirely if name is "". An "ntor" wrapper is given
+// this module's own NodeKey and a PeerPublicKey resolver backed by
+// SetPeerKeys.
+func (o *OOBModule) SetObfuscator(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if name == "" {
+		o.Wrapper = nil
+		return nil
+	}
+	w, err := obfs.Get(name)
+	if err != nil {
+		return fmt.Errorf("oob: set obfuscator: %w", err)
+	}
+	if _, ok := w.(*obfs.NtorWrapper); ok {
+		w = &obfs.NtorWrapper{NodeKey: o.NodeKey, PeerPublicKey: o.resolvePeerKey}
+	}
+	o.Wrapper = w
+	return nil
+}
+
+// wrapClient obfuscates a freshly dialed conn through o.Wrapper, if set.
+func (o *OOBModule) wrapClient(conn net.Conn) (net.Conn, error) {
+	o.mu.Lock()
+	wrapper := o.Wrapper
+	o.mu.Unlock()
+	if wrapper == nil {
+		return conn, nil
+	}
+	wrapped, err := wrapper.Client(conn)
+	if err != nil {
+		return nil, fmt.Errorf("obfs client handshake: %w", err)
+	}
+	return wrapped, nil
+}
+
+// wrapServer obfuscates a freshly accepted conn through o.Wrapper, if set.
+func (o *OOBModule) wrapServer(conn net.Conn) (net.Conn, error) {
+	o.mu.Lock()
+	wrapper := o.Wrapper
+	o.mu.Unlock()
+	if wrapper == nil {
+		return conn, nil
+	}
+	wrapped, err := wrapper.Server(conn)
+	if err != nil {
+		return nil, fmt.Errorf("obfs server handshake: %w", err)
+	}
+	return wrapped, nil
+}
+
+// fileConfig is the minimal peers-only config format read by
+// NewOOBModule, ahead of the full HJSON config.
+type fileConfig struct {
+	Peers []string `json:"peers"`
+}
+
+// isValidYggdrasilAddress validates if a given peer address is a valid Yggdrasil address.
+func isValidYggdrasilAddress(address string) bool {
+	yggPattern := `^[a-fA-F0-9:]+$` // Simplified regex for Yggdrasil addresses
+	matched, _ := regexp.MatchString(yggPattern, address)
+	return matched
+}
+
+// generateSelfSignedCert generates a self-signed TLS certificate used to
+// bootstrap the Yggdrasil core and QUIC transport, returning the ed25519
+// public key alongside it as this node's long-term identity.
+func generateSelfSignedCert() (tls.Certificate, ed25519.PublicKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1)}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv.Seed()})
+	cert, err := tls.X509KeyPair(pemCert, pemKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cert, pub
+}
+
+// NewOOBModule initializes the QUIC transport over Yggdrasil, loading the
+// peer list from a JSON config file at configPath.
+func NewOOBModule(configPath string) (*OOBModule, error) {
+	return newOOBModule(loadPeers(configPath))
+}
+
+// NewOOBModuleFromPeers initializes the QUIC transport over Yggdrasil
+// with an already-loaded peer list, for callers (such as the config
+// package's HJSON loader) that parse their own config format.
+func NewOOBModuleFromPeers(peers []string) (*OOBModule, error) {
+	return newOOBModule(peers)
+}
+
+func newOOBModule(peers []string) (*OOBModule, error) {
+	cert, pub := generateSelfSignedCert()
+	logger := gologme.New(os.Stdout, "core: ", gologme.LstdFlags)
+	yggNode, err := core.New(&cert, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Yggdrasil core: %v", err)
+	}
+
+	quicTransport, err := yggquic.New(yggNode, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start QUIC transport: %v", err)
+	}
+
+	nodeKey, err := obfs.GenerateNodeKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate obfuscation key: %v", err)
+	}
+
+	o := &OOBModule{
+		Node:      yggNode,
+		Transport: quicTransport,
+		Peers:     peers,
+		NodeKey:   nodeKey,
+		PublicKey: pub,
+		StartTime: time.Now(),
+	}
+	go o.serve()
+	return o, nil
+}
+
+// serve loops Transport.Accept, handing each incoming OOB stream off to
+// HandleOOBSession so this node can act as an exit for its peers, not
+// just dial out through them. It runs for the lifetime of the module;
+// Transport.Accept only returns an error once the transport is closed.
+func (o *OOBModule) serve() {
+	for {
+		conn, err := o.Transport.Accept()
+		if err != nil {
+			log.Println("❌ OOB transport accept loop stopped:", err)
+			return
+		}
+		go o.HandleOOBSession(conn)
+	}
+}
+
+func loadPeers(configPath string) []string {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		log.Printf("⚠️ Could not read OOB config %s: %v", configPath, err)
+		return nil
+	}
+	var cfg fileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Printf("⚠️ Could not parse OOB config %s: %v", configPath, err)
+		return nil
+	}
+	return cfg.Peers
+}
+
+// SetPeers replaces the running peer list with updated, logging which
+// peers were added and removed so a config reload's effect is visible.
+func (o *OOBModule) SetPeers(updated []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	current := make(map[string]bool, len(o.Peers))
+	for _, p := range o.Peers {
+		current[p] = true
+	}
+	wanted := make(map[string]bool, len(updated))
+	for _, p := range updated {
+		wanted[p] = true
+	}
+
+	for p := range wanted {
+		if !current[p] {
+			log.Println("🔹 OOB peer added:", p)
+		}
+	}
+	for p := range current {
+		if !wanted[p] {
+			log.Println("🔹 OOB peer removed:", p)
+		}
+	}
+
+	o.Peers = updated
+}
+
+// SetExitPolicy replaces the running ExitPolicy, used by ExitPeer to
+// pick among Peers.
+func (o *OOBModule) SetExitPolicy(policy string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ExitPolicy = policy
+}
+
+// CanConnect reports whether peer is currently reachable over the OOB transport.
+func (o *OOBModule) CanConnect(peer string) bool {
+	if !isValidYggdrasilAddress(peer) {
+		return false
+	}
+	conn, err := o.Transport.Dial("yggdrasil", peer)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// SendOOBRequest sends a request to an OOB peer and waits for a response.
+func (o *OOBModule) SendOOBRequest(peer, requestID, data string) (string, error) {
+	if !isValidYggdrasilAddress(peer) {
+		return "", fmt.Errorf("invalid Yggdrasil address: %s", peer)
+	}
+
+	payload, err := json.Marshal(requestPayload{Data: data})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	conn, err := o.Transport.Dial("yggdrasil", peer)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to peer %s: %v", peer, err)
+	}
+	defer conn.Close()
+
+	conn, err = o.wrapClient(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to negotiate transport with peer %s: %v", peer, err)
+	}
+
+	// Register the waiter before sending, so a fast response can never
+	// race ahead of the Store call.
+	pr := &pendingRequest{ch: make(chan string, 1), started: time.Now()}
+	o.requestMap.Store(requestID, pr)
+	defer o.requestMap.Delete(requestID)
+
+	if err := writeFrame(conn, envelope{Type: kindRequest, RequestID: requestID, Payload: payload}); err != nil {
+		return "", fmt.Errorf("failed to send data: %v", err)
+	}
+
+	// Deliver the peer's matching "response" envelope to the waiter; the
+	// reader stops once conn is closed on return.
+	go func() {
+		for {
+			env, err := readFrame(conn)
+			if err != nil {
+				return
+			}
+			var pl requestPayload
+			if env.Type == kindResponse && env.RequestID == requestID && json.Unmarshal(env.Payload, &pl) == nil {
+				select {
+				case pr.ch <- pl.Data:
+				default:
+				}
+				return
+			}
+		}
+	}()
+
+	select {
+	case response := <-pr.ch:
+		atomic.StoreInt64(&o.statFor(peer).rtt, int64(time.Since(pr.started)))
+		return response, nil
+	case <-time.After(5 * time.Second):
+		return "", fmt.Errorf("timeout waiting for response")
+	}
+}
+
+// OpenExit asks peer to dial target (host:port) and returns a net.Conn
+// over the OOB stream that carries the caller's bytes as "data"
+// envelopes, so the caller can read/write it as a raw bidirectional
+// connection to that target.
+func (o *OOBModule) OpenExit(peer, target string) (net.Conn, error) {
+	if !isValidYggdrasilAddress(peer) {
+		return nil, fmt.Errorf("invalid Yggdrasil address: %s", peer)
+	}
+
+	rawConn, err := o.Transport.Dial("yggdrasil", peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer %s: %v", peer, err)
+	}
+	conn, err := o.wrapClient(rawConn)
+	if err != nil {
+		rawConn.Close()
+		return nil, fmt.Errorf("failed to negotiate transport with peer %s: %v", peer, err)
+	}
+
+	requestID := fmt.Sprintf("%d", time.Now().UnixNano())
+	payload, err := json.Marshal(dialPayload{Target: target})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to encode dial request: %v", err)
+	}
+	if err := writeFrame(conn, envelope{Type: kindDial, RequestID: requestID, Payload: payload}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send dial request: %v", err)
+	}
+	return &framedConn{Conn: conn, requestID: requestID, stat: o.statFor(peer)}, nil
+}
+
+// HandleOOBSession handles incoming OOB requests. A single stream may
+// multiplex several "request" envelopes (one per in-flight RequestID)
+// before being torn down by a "dial" or "close" envelope.
+func (o *OOBModule) HandleOOBSession(conn net.Conn) {
+	defer conn.Close()
+
+	conn, err := o.wrapServer(conn)
+	if err != nil {
+		log.Println("❌ Failed to negotiate transport:", err)
+		return
+	}
+
+	for {
+		env, err := readFrame(conn)
+		if err != nil {
+			if err != io.EOF {
+				log.Println("❌ Failed to read OOB frame:", err)
+			}
+			return
+		}
+
+		switch env.Type {
+		case kindDial:
+			o.handleDial(conn, env)
+			return
+		case kindClose:
+			return
+		case kindRequest:
+			o.handleRequest(conn, env)
+		default:
+			log.Printf("⚠️ Ignoring unexpected frame type %q for request %s", env.Type, env.RequestID)
+		}
+	}
+}
+
+// handleRequest answers a "request" envelope with a "response" envelope,
+// then hands the decoded response to any local waiter in requestMap.
+func (o *OOBModule) handleRequest(conn net.Conn, env envelope) {
+	var pl requestPayload
+	if err := json.Unmarshal(env.Payload, &pl); err != nil {
+		log.Println("❌ Failed to parse request payload:", err)
+		return
+	}
+
+	log.Printf("{\"event\": \"oob_request_received\", \"request_id\": \"%s\", \"data\": \"%s\"}", env.RequestID, pl.Data)
+	response := fmt.Sprintf("ACK: %s", pl.Data)
+
+	respPayload, err := json.Marshal(requestPayload{Data: response})
+	if err != nil {
+		log.Println("❌ Failed to encode response:", err)
+		return
+	}
+	if err := writeFrame(conn, envelope{Type: kindResponse, RequestID: env.RequestID, Payload: respPayload}); err != nil {
+		log.Println("❌ Failed to write response frame:", err)
+		return
+	}
+
+	// Store response for requestor if it's an awaited request
+	if v, ok := o.requestMap.Load(env.RequestID); ok {
+		pr := v.(*pendingRequest)
+		select {
+		case pr.ch <- response:
+		default:
+			log.Println("⚠️ Response channel was not ready, avoiding deadlock")
+		}
+	}
+}
+
+// handleDial opens a TCP connection to pl.Target and splices bytes
+// bidirectionally with the OOB stream (deframed through a framedConn),
+// acting as the exit node for the requesting peer.
+func (o *OOBModule) handleDial(conn net.Conn, env envelope) {
+	var pl dialPayload
+	if err := json.Unmarshal(env.Payload, &pl); err != nil {
+		log.Println("❌ Malformed dial request:", err)
+		return
+	}
+
+	target, err := net.Dial("tcp", pl.Target)
+	if err != nil {
+		log.Println("❌ Exit dial failed:", err)
+		return
+	}
+	defer target.Close()
+
+	log.Printf("{\"event\": \"oob_dial\", \"request_id\": \"%s\", \"target\": \"%s\"}", env.RequestID, pl.Target)
+
+	fc := &framedConn{Conn: conn, requestID: env.RequestID}
+	done := make(chan struct{})
+	go func() {
+		io.Copy(target, fc)
+		close(done)
+	}()
+	io.Copy(fc, target)
+	<-done
+}
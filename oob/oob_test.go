@@ -0,0 +1,109 @@
+package oob
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func pipe() (net.Conn, net.Conn) {
+	return net.Pipe()
+}
+
+func TestReadFrameRejectsOversizedLength(t *testing.T) {
+	client, server := pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		hdr := make([]byte, 4)
+		binary.BigEndian.PutUint32(hdr, maxFrameSize+1)
+		client.Write(hdr)
+	}()
+
+	if _, err := readFrame(server); err == nil {
+		t.Fatal("readFrame accepted a length prefix above maxFrameSize")
+	}
+}
+
+func TestWriteFrameRejectsOversizedBody(t *testing.T) {
+	client, server := pipe()
+	defer client.Close()
+	defer server.Close()
+
+	payload, err := json.Marshal(dataPayload{Chunk: make([]byte, maxFrameSize)})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	err = writeFrame(client, envelope{Type: kindData, RequestID: "r1", Payload: payload})
+	if err == nil {
+		t.Fatal("writeFrame accepted a body above maxFrameSize")
+	}
+}
+
+func TestWriteReadFrameRoundTrip(t *testing.T) {
+	client, server := pipe()
+	defer client.Close()
+	defer server.Close()
+
+	want := envelope{Type: kindRequest, RequestID: "r1", Payload: json.RawMessage(`{"data":"hello"}`)}
+	go writeFrame(client, want)
+
+	got, err := readFrame(server)
+	if err != nil {
+		t.Fatalf("readFrame: %v", err)
+	}
+	if got.Type != want.Type || got.RequestID != want.RequestID {
+		t.Fatalf("round-tripped envelope mismatch: got %+v, want %+v", got, want)
+	}
+}
+
+// TestHandleOOBSessionDialRoundTrip exercises the server-side dial path
+// that serve() wires every accepted stream into: a "dial" envelope
+// followed by "data" envelopes should splice through to a real TCP
+// target and back, exactly as an exit peer is expected to behave for a
+// SOCKS5/MITM client driving OpenExit on the other end.
+func TestHandleOOBSessionDialRoundTrip(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer echo.Close()
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	client, server := pipe()
+	o := &OOBModule{}
+	go o.HandleOOBSession(server)
+
+	payload, err := json.Marshal(dialPayload{Target: echo.Addr().String()})
+	if err != nil {
+		t.Fatalf("marshal dial payload: %v", err)
+	}
+	if err := writeFrame(client, envelope{Type: kindDial, RequestID: "r1", Payload: payload}); err != nil {
+		t.Fatalf("writeFrame dial: %v", err)
+	}
+
+	fc := &framedConn{Conn: client, requestID: "r1"}
+	if _, err := fc.Write([]byte("ping")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	fc.Conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(fc, buf); err != nil {
+		t.Fatalf("read echo: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("echoed data mismatch: got %q", buf)
+	}
+}
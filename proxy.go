@@ -2,31 +2,114 @@ package main
 
 import (
 	"crypto/tls"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"oob"
+	"os"
+	"sync"
 	"time"
+
+	"github.com/immartian/relaygg/admin"
+	"github.com/immartian/relaygg/config"
+	"github.com/immartian/relaygg/oob"
+	"github.com/immartian/relaygg/socks5"
 )
 
-// TLSProxy handles the actual proxy functionality.
+// TLSProxy handles the actual proxy functionality. In MITM mode it mints
+// a leaf certificate per SNI from a local CA and terminates TLS locally,
+// dialing the real origin through an OOB exit peer.
 type TLSProxy struct {
-	OOB *oob.OOBModule
+	OOB  *oob.OOBModule
+	CA   *certAuthority
+	MITM MITMConfig
+
+	mu       sync.Mutex
+	listener net.Listener
+	stopped  bool
+	sessions map[uint64]*session
+	nextID   uint64
+}
+
+// session describes one in-flight MITM connection, for admin/getSessions.
+type session struct {
+	sni     string
+	exit    string
+	started time.Time
 }
 
-// Start runs the TLS proxy.
+// SessionSummary is the admin-facing view of a session.
+type SessionSummary struct {
+	SNI  string        `json:"sni"`
+	Exit string        `json:"exit"`
+	Age  time.Duration `json:"age"`
+}
+
+// Sessions returns a snapshot of every TLS connection currently being
+// proxied.
+func (p *TLSProxy) Sessions() []SessionSummary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]SessionSummary, 0, len(p.sessions))
+	for _, s := range p.sessions {
+		out = append(out, SessionSummary{SNI: s.sni, Exit: s.exit, Age: time.Since(s.started)})
+	}
+	return out
+}
+
+// trackSession registers a new session for sni/exit and returns a
+// function that removes it once the connection ends.
+func (p *TLSProxy) trackSession(sni, exit string) func() {
+	p.mu.Lock()
+	if p.sessions == nil {
+		p.sessions = make(map[uint64]*session)
+	}
+	id := p.nextID
+	p.nextID++
+	p.sessions[id] = &session{sni: sni, exit: exit, started: time.Now()}
+	p.mu.Unlock()
+
+	return func() {
+		p.mu.Lock()
+		delete(p.sessions, id)
+		p.mu.Unlock()
+	}
+}
+
+// NewTLSProxy builds a TLSProxy backed by o, loading (or generating on
+// first run) the local MITM CA described by cfg.
+func NewTLSProxy(o *oob.OOBModule, cfg MITMConfig) (*TLSProxy, error) {
+	ca, err := loadOrCreateCA(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &TLSProxy{OOB: o, CA: ca, MITM: cfg}, nil
+}
+
+// Start runs the TLS proxy until Stop is called.
 func (p *TLSProxy) Start(localAddr string) {
 	listener, err := net.Listen("tcp", localAddr)
 	if err != nil {
 		log.Fatalf("❌ Failed to start TLS Proxy: %v", err)
 	}
+	p.mu.Lock()
+	p.listener = listener
+	p.stopped = false
+	p.mu.Unlock()
 	defer listener.Close()
 	fmt.Println("🔹 TLS Proxy listening on", localAddr)
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			p.mu.Lock()
+			stopped := p.stopped
+			p.mu.Unlock()
+			if stopped {
+				return
+			}
 			log.Println("❌ Connection error:", err)
 			continue
 		}
@@ -34,20 +117,35 @@ func (p *TLSProxy) Start(localAddr string) {
 	}
 }
 
-// handleTLSConnection manages TLS handshakes and data relay.
+// Stop closes the proxy's listening socket so Start returns, without
+// touching connections already accepted and being relayed.
+func (p *TLSProxy) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	if p.listener != nil {
+		p.listener.Close()
+	}
+}
+
+// Rebind stops the proxy's current listener and starts a new one on
+// addr, leaving in-flight connections untouched.
+func (p *TLSProxy) Rebind(addr string) {
+	p.Stop()
+	go p.Start(addr)
+}
+
+// handleTLSConnection sniffs the SNI off the incoming ClientHello, mints
+// a matching leaf certificate, completes the client-facing TLS handshake
+// locally, then dials the real origin through an OOB exit peer and
+// splices the two plaintext streams together.
 func (p *TLSProxy) handleTLSConnection(clientConn net.Conn) {
 	defer clientConn.Close()
 
-	// Read initial TLS handshake (ClientHello)
-	clientHello := make([]byte, 4096)
-	n, err := clientConn.Read(clientHello)
-	if err != nil {
-		log.Println("❌ ERROR: Failed to read ClientHello:", err)
-		return
-	}
-
-	// Extract SNI from ClientHello
-	realSNI, err := extractSNI(clientHello[:n])
+	// Read and reassemble the ClientHello, which may arrive split across
+	// several TLS records and/or several TCP segments. wireBytes is
+	// replayed below so tls.Server can read the same ClientHello itself.
+	_, wireBytes, realSNI, err := readClientHello(clientConn)
 	if err != nil {
 		log.Println("❌ ERROR: Failed to extract SNI:", err)
 		return
@@ -55,45 +153,232 @@ func (p *TLSProxy) handleTLSConnection(clientConn net.Conn) {
 
 	fmt.Println("🔹 Client requested SNI:", realSNI)
 
-	// Send real SNI over OOB and wait for real ServerHello
-	reqID := fmt.Sprintf("%d", time.Now().UnixNano())
-	realServerHello, err := p.OOB.SendOOBRequest(p.OOB.Peers[0], reqID, realSNI)
-	if err != nil {
-		log.Println("❌ OOB request failed:", err)
+	clientTLS := tls.Server(&replayConn{Conn: clientConn, replay: wireBytes}, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return p.CA.leafFor(hello.ServerName)
+		},
+		CipherSuites: p.MITM.CipherSuites,
+	})
+	if err := clientTLS.Handshake(); err != nil {
+		log.Println("❌ ERROR: MITM handshake with client failed:", err)
 		return
 	}
+	defer clientTLS.Close()
 
-	// Inject the real ServerHello response back to the client
-	_, err = clientConn.Write(realServerHello)
+	exitPeer, ok := p.OOB.ExitPeer()
+	if !ok {
+		log.Println("❌ ERROR: No OOB exit peer configured")
+		return
+	}
+	exitConn, err := p.OOB.OpenExit(exitPeer, realSNI+":443")
 	if err != nil {
-		log.Println("❌ ERROR: Failed to send real ServerHello to client:", err)
+		log.Println("❌ ERROR: Failed to dial origin via OOB exit:", err)
 		return
 	}
+	defer exitConn.Close()
+
+	untrack := p.trackSession(realSNI, exitPeer)
+	defer untrack()
 
-	// After handshake, establish a real TLS tunnel
-	targetConn, err := tls.Dial("tcp", realSNI+":443", &tls.Config{
+	originTLS := tls.Client(exitConn, &tls.Config{
 		ServerName:         realSNI,
 		InsecureSkipVerify: true,
 	})
-	if err != nil {
-		log.Println("❌ ERROR: Failed to connect to real server:", err)
+	if err := originTLS.Handshake(); err != nil {
+		log.Println("❌ ERROR: TLS handshake with origin failed:", err)
 		return
 	}
-	defer targetConn.Close()
+	defer originTLS.Close()
 
 	// Start bidirectional data relay
-	go io.Copy(targetConn, clientConn)
-	io.Copy(clientConn, targetConn)
+	go io.Copy(originTLS, clientTLS)
+	io.Copy(clientTLS, originTLS)
+}
+
+// replayConn replays bytes already consumed from Conn before falling
+// through to it, letting tls.Server re-read a ClientHello that was
+// already pulled off the wire for SNI sniffing.
+type replayConn struct {
+	net.Conn
+	replay []byte
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	if len(c.replay) > 0 {
+		n := copy(p, c.replay)
+		c.replay = c.replay[n:]
+		return n, nil
+	}
+	return c.Conn.Read(p)
+}
+
+// newAdminServer registers relaygg's admin commands against the running
+// oobModule and proxy, for inspection and management via relayggctl.
+func newAdminServer(oobModule *oob.OOBModule, proxy *TLSProxy) *admin.Server {
+	s := admin.NewServer()
+
+	s.AddHandler("getSelf", nil, func(json.RawMessage) (interface{}, error) {
+		return oobModule.Self(), nil
+	})
+	s.AddHandler("getPeers", nil, func(json.RawMessage) (interface{}, error) {
+		return oobModule.PeerSummaries(), nil
+	})
+	s.AddHandler("addPeer", []string{"address"}, func(raw json.RawMessage) (interface{}, error) {
+		args, err := decodeAdminArgs(raw, 1)
+		if err != nil {
+			return nil, err
+		}
+		return nil, oobModule.AddPeer(args[0])
+	})
+	s.AddHandler("removePeer", []string{"address"}, func(raw json.RawMessage) (interface{}, error) {
+		args, err := decodeAdminArgs(raw, 1)
+		if err != nil {
+			return nil, err
+		}
+		oobModule.RemovePeer(args[0])
+		return nil, nil
+	})
+	s.AddHandler("getRequests", nil, func(json.RawMessage) (interface{}, error) {
+		return oobModule.Requests(), nil
+	})
+	s.AddHandler("getSessions", nil, func(json.RawMessage) (interface{}, error) {
+		return proxy.Sessions(), nil
+	})
+	s.AddHandler("flushCertCache", nil, func(json.RawMessage) (interface{}, error) {
+		proxy.CA.flushCache()
+		return nil, nil
+	})
+
+	return s
 }
 
-// extractSNI parses ClientHello to extract the SNI field.
-func extractSNI(clientHello []byte) (string, error) {
-	// TODO: Implement a proper ClientHello parser to extract SNI
-	return "example.com", nil
+// decodeAdminArgs unmarshals raw (a JSON-encoded []string, as produced by
+// admin.Server.AddHandler) and checks it carries exactly want arguments.
+func decodeAdminArgs(raw json.RawMessage, want int) ([]string, error) {
+	var args []string
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("decoding admin args: %w", err)
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("expected %d argument(s), got %d", want, len(args))
+	}
+	return args, nil
 }
 
 func main() {
-	oobModule, _ := oob.NewOOBModule("config.json")
-	proxy := TLSProxy{OOB: oobModule}
-	proxy.Start("127.0.0.1:8443")
+	configPath := flag.String("useconf", "relaygg.hjson", "path to the HJSON config file")
+	genConf := flag.Bool("genconf", false, "print default config (with a fresh keypair) as HJSON and exit")
+	normaliseConf := flag.Bool("normaliseconf", false, "parse -useconf and print it back out as canonical HJSON")
+	exportCA := flag.Bool("export-ca", false, "print the MITM CA certificate as PEM (generating it first if needed) and exit")
+	flag.Parse()
+
+	if *exportCA {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("❌ Failed to load %s: %v", *configPath, err)
+		}
+		mitmCfg := defaultMITMConfig()
+		if cfg.MITMCAPath != "" {
+			mitmCfg.CACertPath = cfg.MITMCAPath
+		}
+		ca, err := loadOrCreateCA(mitmCfg)
+		if err != nil {
+			log.Fatalf("❌ Failed to load MITM CA: %v", err)
+		}
+		os.Stdout.Write(ca.exportCAPEM())
+		return
+	}
+
+	if *genConf {
+		cfg, err := config.Default()
+		if err != nil {
+			log.Fatalf("❌ Failed to generate default config: %v", err)
+		}
+		out, err := config.Marshal(cfg)
+		if err != nil {
+			log.Fatalf("❌ Failed to render default config: %v", err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	if *normaliseConf {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("❌ Failed to load %s: %v", *configPath, err)
+		}
+		out, err := config.Marshal(cfg)
+		if err != nil {
+			log.Fatalf("❌ Failed to render %s: %v", *configPath, err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to load %s: %v", *configPath, err)
+	}
+
+	oobModule, err := oob.NewOOBModuleFromPeers(cfg.Peers)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize OOB module: %v", err)
+	}
+	oobModule.SetExitPolicy(cfg.ExitPolicy)
+	oobModule.SetPeerKeys(cfg.PeerKeys)
+	if cfg.Obfuscator != "" {
+		if err := oobModule.SetObfuscator(cfg.Obfuscator); err != nil {
+			log.Fatalf("❌ Failed to set obfuscator: %v", err)
+		}
+	}
+
+	mitmCfg := defaultMITMConfig()
+	if cfg.MITMCAPath != "" {
+		mitmCfg.CACertPath = cfg.MITMCAPath
+	}
+	proxy, err := NewTLSProxy(oobModule, mitmCfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize TLS Proxy: %v", err)
+	}
+
+	var socksServer *socks5.Server
+	if len(oobModule.Peers) > 0 {
+		socksServer = socks5.NewServer(oobModule, nil)
+		go func() {
+			if err := socksServer.ListenAndServe(cfg.SOCKS5Listen); err != nil {
+				log.Println("❌ SOCKS5 server stopped:", err)
+			}
+		}()
+	}
+
+	if cfg.AdminSocket != "" {
+		adminServer := newAdminServer(oobModule, proxy)
+		go func() {
+			if err := adminServer.ListenUnix(cfg.AdminSocket); err != nil {
+				log.Println("❌ Admin socket stopped:", err)
+			}
+		}()
+	}
+
+	tlsProxyListen := cfg.TLSProxyListen
+	go config.WatchSIGHUP(*configPath, func(newCfg config.Config) {
+		oobModule.SetPeers(newCfg.Peers)
+		oobModule.SetExitPolicy(newCfg.ExitPolicy)
+		oobModule.SetPeerKeys(newCfg.PeerKeys)
+		if newCfg.Obfuscator != cfg.Obfuscator {
+			if err := oobModule.SetObfuscator(newCfg.Obfuscator); err != nil {
+				log.Println("❌ Failed to set obfuscator:", err)
+			}
+		}
+
+		if newCfg.TLSProxyListen != cfg.TLSProxyListen {
+			proxy.Rebind(newCfg.TLSProxyListen)
+		}
+		if socksServer != nil && newCfg.SOCKS5Listen != cfg.SOCKS5Listen {
+			socksServer.Rebind(newCfg.SOCKS5Listen)
+		}
+		cfg = newCfg
+	})
+
+	proxy.Start(tlsProxyListen)
 }
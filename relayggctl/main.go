@@ -0,0 +1,76 @@
+// Command relayggctl is a thin client for relaygg's admin control
+// socket: it sends one named request and prints back the JSON result.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+)
+
+type request struct {
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+}
+
+type response struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+func main() {
+	socketPath := flag.String("socket", "/var/run/relaygg/admin.sock", "path to relaygg's admin Unix-domain socket")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [-socket path] <command> [args...]\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "commands: getSelf, getPeers, addPeer <addr>, removePeer <addr>, getRequests, getSessions, flushCertCache")
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	conn, err := net.Dial("unix", *socketPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ connecting to %s: %v\n", *socketPath, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	req := request{Name: flag.Arg(0), Args: flag.Args()[1:]}
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ encoding request: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := conn.Write(append(encoded, '\n')); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ sending request: %v\n", err)
+		os.Exit(1)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		fmt.Fprintf(os.Stderr, "❌ no response from %s\n", *socketPath)
+		os.Exit(1)
+	}
+	var resp response
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ decoding response: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.Error != "" {
+		fmt.Fprintln(os.Stderr, "❌", resp.Error)
+		os.Exit(1)
+	}
+
+	out, err := json.MarshalIndent(resp.Result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ formatting result: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(out))
+}
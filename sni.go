@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	recordTypeHandshake      = 0x16
+	handshakeTypeClientHello = 0x01
+	extensionServerName      = 0x0000
+	serverNameTypeHostName   = 0x00
+	maxExtensionsSize        = 16 * 1024
+	maxRecordSize            = 0x4000 // 16 KiB, per RFC 8446 5.1
+)
+
+var (
+	errNotHandshake     = errors.New("sni: record is not a TLS handshake record")
+	errNotClientHello   = errors.New("sni: handshake message is not a ClientHello")
+	errNoServerName     = errors.New("sni: ClientHello has no server_name extension")
+	errTruncatedMessage = errors.New("sni: truncated ClientHello")
+	errExtensionsTooBig = errors.New("sni: extensions block exceeds 16 KiB")
+)
+
+// readClientHello reads TLS records off r, reassembling the handshake
+// layer across as many records (and as many underlying Read calls) as
+// it takes to collect a complete ClientHello message. It returns the raw
+// ClientHello handshake bytes unchanged, so callers can forward them
+// verbatim, the SNI host name found in the server_name extension, and
+// wire — the exact bytes consumed from r (record headers included), so
+// a caller that needs to hand r to something else (e.g. tls.Server) can
+// replay them first.
+func readClientHello(r io.Reader) (raw, wire []byte, sni string, err error) {
+	var handshake []byte
+	need := 0 // total handshake bytes required once the header has been seen
+
+	for need == 0 || len(handshake) < need {
+		var recHdr [5]byte
+		if _, err := io.ReadFull(r, recHdr[:]); err != nil {
+			return nil, nil, "", fmt.Errorf("sni: reading record header: %w", err)
+		}
+		if recHdr[0] != recordTypeHandshake {
+			return nil, nil, "", errNotHandshake
+		}
+		recLen := int(binary.BigEndian.Uint16(recHdr[3:5]))
+		if recLen == 0 || recLen > maxRecordSize {
+			return nil, nil, "", fmt.Errorf("sni: invalid record length %d", recLen)
+		}
+
+		payload := make([]byte, recLen)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, nil, "", fmt.Errorf("sni: reading record payload: %w", err)
+		}
+		wire = append(wire, recHdr[:]...)
+		wire = append(wire, payload...)
+		handshake = append(handshake, payload...)
+
+		if need == 0 && len(handshake) >= 4 {
+			if handshake[0] != handshakeTypeClientHello {
+				return nil, nil, "", errNotClientHello
+			}
+			need = 4 + (int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3]))
+		}
+	}
+
+	raw = handshake[:need]
+	sni, err = parseClientHello(raw[4:])
+	return raw, wire, sni, err
+}
+
+// parseClientHello walks a ClientHello body (handshake header already
+// stripped) to locate the server_name extension: client_version,
+// random, session_id, cipher_suites, compression_methods, then the
+// extensions vector.
+func parseClientHello(body []byte) (string, error) {
+	c := &byteCursor{b: body}
+
+	if _, err := c.take(2); err != nil { // client_version
+		return "", err
+	}
+	if _, err := c.take(32); err != nil { // client_random
+		return "", err
+	}
+
+	sessionIDLen, err := c.take(1)
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.take(int(sessionIDLen[0])); err != nil {
+		return "", err
+	}
+
+	cipherSuitesLen, err := c.take(2)
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.take(int(binary.BigEndian.Uint16(cipherSuitesLen))); err != nil {
+		return "", err
+	}
+
+	compressionLen, err := c.take(1)
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.take(int(compressionLen[0])); err != nil {
+		return "", err
+	}
+
+	if c.remaining() == 0 {
+		return "", errNoServerName // pre-TLS1.0-style ClientHello, no extensions at all
+	}
+
+	extTotalLen, err := c.take(2)
+	if err != nil {
+		return "", err
+	}
+	extTotal := int(binary.BigEndian.Uint16(extTotalLen))
+	if extTotal > maxExtensionsSize {
+		return "", errExtensionsTooBig
+	}
+	extensions, err := c.take(extTotal)
+	if err != nil {
+		return "", err
+	}
+
+	e := &byteCursor{b: extensions}
+	for e.remaining() > 0 {
+		hdr, err := e.take(4)
+		if err != nil {
+			return "", err
+		}
+		extType := binary.BigEndian.Uint16(hdr[0:2])
+		extLen := int(binary.BigEndian.Uint16(hdr[2:4]))
+		data, err := e.take(extLen)
+		if err != nil {
+			return "", err
+		}
+		if extType != extensionServerName {
+			continue // includes GREASE extension values (0x?a?a), which we simply skip over
+		}
+		return parseServerNameExtension(data)
+	}
+
+	return "", errNoServerName
+}
+
+// parseServerNameExtension unpacks the server_name_list and returns the
+// first host_name entry (the only entry type TLS 1.2/1.3 clients send).
+func parseServerNameExtension(data []byte) (string, error) {
+	c := &byteCursor{b: data}
+
+	listLen, err := c.take(2)
+	if err != nil {
+		return "", err
+	}
+	list, err := c.take(int(binary.BigEndian.Uint16(listLen)))
+	if err != nil {
+		return "", err
+	}
+
+	l := &byteCursor{b: list}
+	for l.remaining() > 0 {
+		nameType, err := l.take(1)
+		if err != nil {
+			return "", err
+		}
+		nameLen, err := l.take(2)
+		if err != nil {
+			return "", err
+		}
+		name, err := l.take(int(binary.BigEndian.Uint16(nameLen)))
+		if err != nil {
+			return "", err
+		}
+		if nameType[0] != serverNameTypeHostName {
+			continue
+		}
+		return string(name), nil
+	}
+
+	return "", errNoServerName
+}
+
+// byteCursor is a minimal bounds-checked reader over an in-memory buffer,
+// used to walk the nested TLV structures inside a ClientHello.
+type byteCursor struct {
+	b   []byte
+	off int
+}
+
+func (c *byteCursor) remaining() int { return len(c.b) - c.off }
+
+func (c *byteCursor) take(n int) ([]byte, error) {
+	if n < 0 || c.remaining() < n {
+		return nil, errTruncatedMessage
+	}
+	v := c.b[c.off : c.off+n]
+	c.off += n
+	return v, nil
+}
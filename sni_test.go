@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// extension is a helper for building raw TLS extensions in tests.
+type extension struct {
+	typ  uint16
+	data []byte
+}
+
+func serverNameExtension(host string) extension {
+	entry := append([]byte{serverNameTypeHostName}, u16(len(host))...)
+	entry = append(entry, []byte(host)...)
+	list := append(u16(len(entry)), entry...)
+	return extension{typ: extensionServerName, data: list}
+}
+
+func u16(n int) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(n))
+	return b
+}
+
+// buildClientHello assembles a minimal but structurally valid ClientHello
+// body (cipher suites and extra extensions vary per caller to emulate
+// different client fingerprints) wrapped in a single TLS handshake record.
+func buildClientHello(cipherSuites []uint16, extra []extension, sni string) []byte {
+	var body bytes.Buffer
+	body.Write([]byte{0x03, 0x03})             // client_version: TLS 1.2
+	body.Write(bytes.Repeat([]byte{0x42}, 32)) // client_random
+	body.WriteByte(0x00)                       // session_id: empty
+
+	var suites bytes.Buffer
+	for _, cs := range cipherSuites {
+		suites.Write(u16(int(cs)))
+	}
+	body.Write(u16(suites.Len()))
+	body.Write(suites.Bytes())
+
+	body.Write([]byte{0x01, 0x00}) // compression_methods: [null]
+
+	var exts bytes.Buffer
+	for _, e := range extra {
+		exts.Write(u16(int(e.typ)))
+		exts.Write(u16(len(e.data)))
+		exts.Write(e.data)
+	}
+	if sni != "" {
+		e := serverNameExtension(sni)
+		exts.Write(u16(int(e.typ)))
+		exts.Write(u16(len(e.data)))
+		exts.Write(e.data)
+	}
+	body.Write(u16(exts.Len()))
+	body.Write(exts.Bytes())
+
+	var handshake bytes.Buffer
+	handshake.WriteByte(handshakeTypeClientHello)
+	length := body.Len()
+	handshake.Write([]byte{byte(length >> 16), byte(length >> 8), byte(length)})
+	handshake.Write(body.Bytes())
+
+	var record bytes.Buffer
+	record.WriteByte(recordTypeHandshake)
+	record.Write([]byte{0x03, 0x01}) // record version
+	record.Write(u16(handshake.Len()))
+	record.Write(handshake.Bytes())
+	return record.Bytes()
+}
+
+func TestReadClientHello_RealCaptureShapes(t *testing.T) {
+	cases := []struct {
+		name         string
+		cipherSuites []uint16
+		extra        []extension
+		sni          string
+	}{
+		{
+			name:         "chrome",
+			cipherSuites: []uint16{0x1301, 0x1302, 0xc02b, 0xc02f},
+			extra:        []extension{{typ: 0x002b, data: []byte{0x02, 0x03, 0x04}}}, // supported_versions
+			sni:          "www.google.com",
+		},
+		{
+			name:         "firefox",
+			cipherSuites: []uint16{0x1301, 0x1303, 0xcca9, 0xcca8},
+			extra:        []extension{{typ: 0x000a, data: []byte{0x00, 0x02, 0x00, 0x1d}}}, // supported_groups
+			sni:          "example.org",
+		},
+		{
+			name:         "curl",
+			cipherSuites: []uint16{0xc02c, 0xc030, 0x009f},
+			extra:        nil,
+			sni:          "api.example.net",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := buildClientHello(tc.cipherSuites, tc.extra, tc.sni)
+			gotRaw, _, gotSNI, err := readClientHello(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("readClientHello: %v", err)
+			}
+			if gotSNI != tc.sni {
+				t.Errorf("sni = %q, want %q", gotSNI, tc.sni)
+			}
+			if !bytes.Equal(gotRaw, raw[5:]) {
+				t.Errorf("raw ClientHello bytes were altered, want them forwarded unchanged")
+			}
+		})
+	}
+}
+
+func TestReadClientHello_GREASE(t *testing.T) {
+	// GREASE values follow the 0x?A?A pattern (RFC 8701) and must be
+	// skipped like any other unrecognized cipher suite/extension.
+	greaseCiphers := []uint16{0x0a0a, 0x1301, 0xc02f}
+	greaseExt := extension{typ: 0xaaaa, data: []byte{0x00}}
+	raw := buildClientHello(greaseCiphers, []extension{greaseExt}, "grease.example.com")
+
+	_, _, sni, err := readClientHello(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("readClientHello: %v", err)
+	}
+	if sni != "grease.example.com" {
+		t.Errorf("sni = %q, want grease.example.com", sni)
+	}
+}
+
+// chunkedReader yields at most n bytes per Read call, simulating a
+// ClientHello split across TCP segment boundaries.
+type chunkedReader struct {
+	b []byte
+	n int
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if len(c.b) == 0 {
+		return 0, io.EOF
+	}
+	max := c.n
+	if max > len(p) {
+		max = len(p)
+	}
+	if max > len(c.b) {
+		max = len(c.b)
+	}
+	copy(p, c.b[:max])
+	c.b = c.b[max:]
+	return max, nil
+}
+
+func TestReadClientHello_FragmentedAcrossSegments(t *testing.T) {
+	raw := buildClientHello([]uint16{0x1301, 0xc02f}, nil, "fragmented.example.com")
+
+	for _, chunkSize := range []int{1, 3, 7} {
+		r := &chunkedReader{b: raw, n: chunkSize}
+		_, _, sni, err := readClientHello(r)
+		if err != nil {
+			t.Fatalf("chunkSize=%d: readClientHello: %v", chunkSize, err)
+		}
+		if sni != "fragmented.example.com" {
+			t.Errorf("chunkSize=%d: sni = %q, want fragmented.example.com", chunkSize, sni)
+		}
+	}
+}
+
+func TestReadClientHello_MultipleHandshakeRecords(t *testing.T) {
+	raw := buildClientHello([]uint16{0x1301, 0xc02f}, nil, "split-record.example.com")
+
+	// Split the single handshake record into two records at an arbitrary
+	// midpoint, each with its own 5-byte record header.
+	const headerLen = 5
+	payload := raw[headerLen:]
+	mid := len(payload) / 2
+
+	rec := func(chunk []byte) []byte {
+		out := []byte{recordTypeHandshake, 0x03, 0x01}
+		out = append(out, u16(len(chunk))...)
+		return append(out, chunk...)
+	}
+
+	var twoRecords bytes.Buffer
+	twoRecords.Write(rec(payload[:mid]))
+	twoRecords.Write(rec(payload[mid:]))
+
+	_, _, sni, err := readClientHello(&twoRecords)
+	if err != nil {
+		t.Fatalf("readClientHello: %v", err)
+	}
+	if sni != "split-record.example.com" {
+		t.Errorf("sni = %q, want split-record.example.com", sni)
+	}
+}
+
+func TestReadClientHello_RejectsNonHandshakeRecord(t *testing.T) {
+	appData := []byte{0x17, 0x03, 0x03, 0x00, 0x01, 0x00} // application_data
+	_, _, _, err := readClientHello(bytes.NewReader(appData))
+	if err != errNotHandshake {
+		t.Errorf("err = %v, want errNotHandshake", err)
+	}
+}
+
+func TestParseClientHello_ExtensionsTooLarge(t *testing.T) {
+	huge := bytes.Repeat([]byte{0x00}, maxExtensionsSize+1)
+	body := append([]byte{0x03, 0x03}, bytes.Repeat([]byte{0x00}, 32)...) // version + random
+	body = append(body, 0x00)                                             // session_id len
+	body = append(body, 0x00, 0x00)                                       // cipher_suites len
+	body = append(body, 0x01, 0x00)                                       // compression_methods
+	body = append(body, u16(len(huge))...)
+	body = append(body, huge...)
+
+	_, err := parseClientHello(body)
+	if err != errExtensionsTooBig {
+		t.Errorf("err = %v, want errExtensionsTooBig", err)
+	}
+}
+
+func TestParseClientHello_NoServerNameExtension(t *testing.T) {
+	raw := buildClientHello([]uint16{0x1301}, nil, "")
+	_, _, _, err := readClientHello(bytes.NewReader(raw))
+	if err != errNoServerName {
+		t.Errorf("err = %v, want errNoServerName", err)
+	}
+}
@@ -0,0 +1,386 @@
+// Package socks5 implements a RFC 1928 SOCKS5 frontend that tunnels
+// CONNECT targets over OOB instead of dialing them directly, letting any
+// SOCKS5-aware client egress through a chosen Yggdrasil exit peer.
+package socks5
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/immartian/relaygg/oob"
+)
+
+const (
+	version5 = 0x05
+
+	methodNoAuth       = 0x00
+	methodUserPass     = 0x02
+	methodNoAcceptable = 0xFF
+
+	userPassVersion = 0x01
+	authSuccess     = 0x00
+	authFailure     = 0x01
+
+	cmdConnect      = 0x01
+	cmdUDPAssociate = 0x03
+
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	replySucceeded           = 0x00
+	replyGeneralFailure      = 0x01
+	replyCommandNotSupported = 0x07
+	replyAddrNotSupported    = 0x08
+)
+
+// Server is a SOCKS5 frontend that tunnels CONNECT requests over OOB,
+// picking an egress peer via OOB.ExitPeer (and so OOB.ExitPolicy) fresh
+// for every connection.
+type Server struct {
+	OOB *oob.OOBModule
+
+	// Credentials, if non-empty, requires RFC 1929 username/password
+	// auth instead of offering no-auth.
+	Credentials map[string]string
+
+	mu       sync.Mutex
+	listener net.Listener
+	stopped  bool
+}
+
+// NewServer builds a Server that tunnels CONNECT targets over o.
+func NewServer(o *oob.OOBModule, creds map[string]string) *Server {
+	return &Server{OOB: o, Credentials: creds}
+}
+
+// ListenAndServe runs the SOCKS5 server on localAddr until Stop is
+// called. Accept errors are logged and the loop keeps accepting; an
+// error is returned only if localAddr cannot be listened on.
+func (s *Server) ListenAndServe(localAddr string) error {
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return fmt.Errorf("socks5: listen: %w", err)
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.stopped = false
+	s.mu.Unlock()
+	defer listener.Close()
+	log.Println("🔹 SOCKS5 listening on", localAddr)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			stopped := s.stopped
+			s.mu.Unlock()
+			if stopped {
+				return nil
+			}
+			log.Println("❌ socks5: accept:", err)
+			continue
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Stop closes the server's listening socket so ListenAndServe returns,
+// without touching connections already accepted and being relayed.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+// Rebind stops the server's current listener and starts a new one on
+// addr, leaving in-flight connections untouched.
+func (s *Server) Rebind(addr string) {
+	s.Stop()
+	go func() {
+		if err := s.ListenAndServe(addr); err != nil {
+			log.Println("❌ socks5: rebind failed:", err)
+		}
+	}()
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	if err := s.negotiateMethod(conn); err != nil {
+		log.Println("❌ socks5: method negotiation:", err)
+		return
+	}
+
+	target, cmd, err := readRequest(conn)
+	if err != nil {
+		log.Println("❌ socks5: request:", err)
+		return
+	}
+
+	switch cmd {
+	case cmdConnect:
+		s.handleConnect(conn, target)
+	case cmdUDPAssociate:
+		s.handleUDPAssociate(conn)
+	default:
+		writeReply(conn, replyCommandNotSupported)
+	}
+}
+
+// negotiateMethod performs the RFC 1928 method selection, followed by
+// the RFC 1929 username/password sub-negotiation when s.Credentials is set.
+func (s *Server) negotiateMethod(conn net.Conn) error {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return fmt.Errorf("reading method request: %w", err)
+	}
+	if hdr[0] != version5 {
+		return fmt.Errorf("unsupported SOCKS version 0x%02x", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return fmt.Errorf("reading methods: %w", err)
+	}
+
+	wantMethod := byte(methodNoAuth)
+	if len(s.Credentials) > 0 {
+		wantMethod = methodUserPass
+	}
+
+	offered := false
+	for _, m := range methods {
+		if m == wantMethod {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		conn.Write([]byte{version5, methodNoAcceptable})
+		return fmt.Errorf("client did not offer required method 0x%02x", wantMethod)
+	}
+	if _, err := conn.Write([]byte{version5, wantMethod}); err != nil {
+		return fmt.Errorf("writing method selection: %w", err)
+	}
+
+	if wantMethod == methodUserPass {
+		return s.authenticate(conn)
+	}
+	return nil
+}
+
+func (s *Server) authenticate(conn net.Conn) error {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return fmt.Errorf("reading auth header: %w", err)
+	}
+	if hdr[0] != userPassVersion {
+		return fmt.Errorf("unsupported auth version 0x%02x", hdr[0])
+	}
+	uname := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, uname); err != nil {
+		return fmt.Errorf("reading username: %w", err)
+	}
+	plen := make([]byte, 1)
+	if _, err := io.ReadFull(conn, plen); err != nil {
+		return fmt.Errorf("reading password length: %w", err)
+	}
+	passwd := make([]byte, plen[0])
+	if _, err := io.ReadFull(conn, passwd); err != nil {
+		return fmt.Errorf("reading password: %w", err)
+	}
+
+	if want, ok := s.Credentials[string(uname)]; !ok || want != string(passwd) {
+		conn.Write([]byte{userPassVersion, authFailure})
+		return fmt.Errorf("authentication failed for user %q", uname)
+	}
+	_, err := conn.Write([]byte{userPassVersion, authSuccess})
+	return err
+}
+
+// readRequest parses a SOCKS5 request (VER CMD RSV ATYP DST.ADDR
+// DST.PORT) and returns the requested command and a "host:port" target.
+func readRequest(conn net.Conn) (target string, cmd byte, err error) {
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return "", 0, fmt.Errorf("reading request header: %w", err)
+	}
+	if hdr[0] != version5 {
+		return "", 0, fmt.Errorf("unsupported SOCKS version 0x%02x", hdr[0])
+	}
+	cmd = hdr[1]
+
+	var host string
+	switch hdr[3] {
+	case atypIPv4:
+		addr := make([]byte, 4)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return "", 0, fmt.Errorf("reading IPv4 address: %w", err)
+		}
+		host = net.IP(addr).String()
+	case atypDomain:
+		lenByte := make([]byte, 1)
+		if _, err := io.ReadFull(conn, lenByte); err != nil {
+			return "", 0, fmt.Errorf("reading domain length: %w", err)
+		}
+		domain := make([]byte, lenByte[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", 0, fmt.Errorf("reading domain: %w", err)
+		}
+		host = string(domain)
+	case atypIPv6:
+		addr := make([]byte, 16)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return "", 0, fmt.Errorf("reading IPv6 address: %w", err)
+		}
+		host = net.IP(addr).String()
+	default:
+		writeReply(conn, replyAddrNotSupported)
+		return "", 0, fmt.Errorf("unsupported address type 0x%02x", hdr[3])
+	}
+
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(conn, portBytes); err != nil {
+		return "", 0, fmt.Errorf("reading port: %w", err)
+	}
+	port := binary.BigEndian.Uint16(portBytes)
+
+	return net.JoinHostPort(host, fmt.Sprintf("%d", port)), cmd, nil
+}
+
+// handleConnect tunnels target through s.OOB's exit peer and splices the
+// client connection with the resulting stream.
+func (s *Server) handleConnect(conn net.Conn, target string) {
+	exitPeer, ok := s.OOB.ExitPeer()
+	if !ok {
+		log.Println("❌ socks5: no OOB exit peer configured")
+		writeReply(conn, replyGeneralFailure)
+		return
+	}
+	exitConn, err := s.OOB.OpenExit(exitPeer, target)
+	if err != nil {
+		log.Println("❌ socks5: exit dial failed:", err)
+		writeReply(conn, replyGeneralFailure)
+		return
+	}
+	defer exitConn.Close()
+
+	if err := writeReply(conn, replySucceeded); err != nil {
+		log.Println("❌ socks5: writing success reply:", err)
+		return
+	}
+
+	go io.Copy(exitConn, conn)
+	io.Copy(conn, exitConn)
+}
+
+// handleUDPAssociate is a minimal UDP ASSOCIATE implementation: it opens
+// a local UDP relay and forwards each datagram directly to its target,
+// bypassing the OOB exit (OOB is stream-oriented and has no datagram
+// mode yet).
+func (s *Server) handleUDPAssociate(conn net.Conn) {
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		log.Println("❌ socks5: udp associate listen:", err)
+		writeReply(conn, replyGeneralFailure)
+		return
+	}
+	defer relay.Close()
+
+	if err := writeUDPReply(conn, relay.LocalAddr().(*net.UDPAddr)); err != nil {
+		log.Println("❌ socks5: writing udp associate reply:", err)
+		return
+	}
+
+	// The UDP association lives as long as the TCP control connection
+	// does; block on it while packets are relayed in the background.
+	go relayUDP(relay)
+	io.Copy(io.Discard, conn)
+}
+
+func relayUDP(relay *net.UDPConn) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, clientAddr, err := relay.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		target, payload, err := decodeUDPRequest(buf[:n])
+		if err != nil {
+			log.Println("❌ socks5: malformed UDP request:", err)
+			continue
+		}
+		targetConn, err := net.Dial("udp", target)
+		if err != nil {
+			log.Println("❌ socks5: udp dial failed:", err)
+			continue
+		}
+		targetConn.Write(payload)
+		targetConn.Close()
+		_ = clientAddr // a full implementation would read the reply and relay it back to clientAddr
+	}
+}
+
+// decodeUDPRequest parses the RFC 1928 section 7 UDP request header
+// (RSV RSV FRAG ATYP DST.ADDR DST.PORT DATA).
+func decodeUDPRequest(b []byte) (target string, payload []byte, err error) {
+	if len(b) < 4 {
+		return "", nil, fmt.Errorf("short UDP request")
+	}
+	atyp := b[3]
+	b = b[4:]
+
+	var host string
+	switch atyp {
+	case atypIPv4:
+		if len(b) < 4 {
+			return "", nil, fmt.Errorf("short IPv4 UDP request")
+		}
+		host = net.IP(b[:4]).String()
+		b = b[4:]
+	case atypDomain:
+		if len(b) < 1 || len(b) < 1+int(b[0]) {
+			return "", nil, fmt.Errorf("short domain UDP request")
+		}
+		n := int(b[0])
+		host = string(b[1 : 1+n])
+		b = b[1+n:]
+	case atypIPv6:
+		if len(b) < 16 {
+			return "", nil, fmt.Errorf("short IPv6 UDP request")
+		}
+		host = net.IP(b[:16]).String()
+		b = b[16:]
+	default:
+		return "", nil, fmt.Errorf("unsupported address type 0x%02x", atyp)
+	}
+
+	if len(b) < 2 {
+		return "", nil, fmt.Errorf("short UDP request port")
+	}
+	port := binary.BigEndian.Uint16(b[:2])
+	return net.JoinHostPort(host, fmt.Sprintf("%d", port)), b[2:], nil
+}
+
+func writeReply(conn net.Conn, code byte) error {
+	reply := []byte{version5, code, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0}
+	_, err := conn.Write(reply)
+	return err
+}
+
+func writeUDPReply(conn net.Conn, addr *net.UDPAddr) error {
+	reply := []byte{version5, replySucceeded, 0x00, atypIPv4}
+	reply = append(reply, addr.IP.To4()...)
+	portBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(portBytes, uint16(addr.Port))
+	reply = append(reply, portBytes...)
+	_, err := conn.Write(reply)
+	return err
+}
@@ -0,0 +1,154 @@
+package socks5
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestReadRequestIPv4(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		req := []byte{version5, cmdConnect, 0x00, atypIPv4, 93, 184, 216, 34}
+		port := make([]byte, 2)
+		binary.BigEndian.PutUint16(port, 443)
+		client.Write(append(req, port...))
+	}()
+
+	target, cmd, err := readRequest(server)
+	if err != nil {
+		t.Fatalf("readRequest: %v", err)
+	}
+	if cmd != cmdConnect {
+		t.Fatalf("got cmd 0x%02x, want cmdConnect", cmd)
+	}
+	if target != "93.184.216.34:443" {
+		t.Fatalf("got target %q", target)
+	}
+}
+
+func TestReadRequestDomain(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		domain := "example.com"
+		req := []byte{version5, cmdConnect, 0x00, atypDomain, byte(len(domain))}
+		req = append(req, []byte(domain)...)
+		port := make([]byte, 2)
+		binary.BigEndian.PutUint16(port, 80)
+		client.Write(append(req, port...))
+	}()
+
+	target, cmd, err := readRequest(server)
+	if err != nil {
+		t.Fatalf("readRequest: %v", err)
+	}
+	if cmd != cmdConnect {
+		t.Fatalf("got cmd 0x%02x, want cmdConnect", cmd)
+	}
+	if target != "example.com:80" {
+		t.Fatalf("got target %q", target)
+	}
+}
+
+func TestReadRequestRejectsBadVersion(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go client.Write([]byte{0x04, cmdConnect, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0})
+
+	if _, _, err := readRequest(server); err == nil {
+		t.Fatal("readRequest accepted a non-SOCKS5 version byte")
+	}
+}
+
+func TestReadRequestRejectsUnsupportedAddrType(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go client.Write([]byte{version5, cmdConnect, 0x00, 0x7F})
+	// readRequest writes a replyAddrNotSupported reply before returning
+	// its error; drain it so that write doesn't block forever.
+	go io.Copy(io.Discard, client)
+
+	if _, _, err := readRequest(server); err == nil {
+		t.Fatal("readRequest accepted an unsupported address type")
+	}
+}
+
+func TestNegotiateMethodNoAuth(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	s := &Server{}
+	errc := make(chan error, 1)
+	go func() { errc <- s.negotiateMethod(server) }()
+
+	client.Write([]byte{version5, 1, methodNoAuth})
+	resp := make([]byte, 2)
+	if _, err := client.Read(resp); err != nil {
+		t.Fatalf("read method selection: %v", err)
+	}
+	if resp[0] != version5 || resp[1] != methodNoAuth {
+		t.Fatalf("got selection %v, want no-auth", resp)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("negotiateMethod: %v", err)
+	}
+}
+
+func TestNegotiateMethodRejectsWhenNoAcceptableMethod(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	s := &Server{Credentials: map[string]string{"alice": "hunter2"}}
+	errc := make(chan error, 1)
+	go func() { errc <- s.negotiateMethod(server) }()
+
+	client.Write([]byte{version5, 1, methodNoAuth})
+	resp := make([]byte, 2)
+	if _, err := client.Read(resp); err != nil {
+		t.Fatalf("read method selection: %v", err)
+	}
+	if resp[1] != methodNoAcceptable {
+		t.Fatalf("got selection %v, want methodNoAcceptable", resp)
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("negotiateMethod did not report the missing required method")
+	}
+}
+
+func TestDecodeUDPRequest(t *testing.T) {
+	req := []byte{0x00, 0x00, 0x00, atypIPv4, 127, 0, 0, 1}
+	port := make([]byte, 2)
+	binary.BigEndian.PutUint16(port, 5353)
+	req = append(req, port...)
+	req = append(req, []byte("payload")...)
+
+	target, payload, err := decodeUDPRequest(req)
+	if err != nil {
+		t.Fatalf("decodeUDPRequest: %v", err)
+	}
+	if target != "127.0.0.1:5353" {
+		t.Fatalf("got target %q", target)
+	}
+	if string(payload) != "payload" {
+		t.Fatalf("got payload %q", payload)
+	}
+}
+
+func TestDecodeUDPRequestRejectsShort(t *testing.T) {
+	if _, _, err := decodeUDPRequest([]byte{0x00, 0x00, 0x00, atypIPv4, 1, 2}); err == nil {
+		t.Fatal("decodeUDPRequest accepted a truncated IPv4 request")
+	}
+}